@@ -25,20 +25,38 @@ func Execute() int {
 }
 
 var (
-	rootCmd = commoncmd.NewRunCmd(
+	rootCmd = commoncmd.NewRunCmdWithOptions(
 		"generate",
 		&projectDirFlagVal,
 		&cfgFlagVal,
 		&verifyFlagVal,
+		cmdOptions,
 	)
 
-	projectDirFlagVal string
-	cfgFlagVal        string
-	verifyFlagVal     bool
+	projectDirFlagVal    string
+	cfgFlagVal           string
+	requireConfigFlagVal bool
+	verifyFlagVal        bool
+
+	cmdOptions = commoncmd.Options{
+		RequireConfigFlagVal: &requireConfigFlagVal,
+	}
 )
 
 func init() {
-	pluginapi.AddProjectDirPFlagPtr(rootCmd.Flags(), &projectDirFlagVal)
-	rootCmd.Flags().StringVar(&cfgFlagVal, "config", "", "the YAML configuration file for the generate task")
+	pluginapi.AddProjectDirPFlagPtr(rootCmd.PersistentFlags(), &projectDirFlagVal)
+	rootCmd.PersistentFlags().StringVar(&cfgFlagVal, "config", "", "the YAML configuration file for the generate task")
+	rootCmd.PersistentFlags().BoolVar(&requireConfigFlagVal, "require-config", false, "fail if no configuration file is specified")
 	rootCmd.Flags().BoolVar(&verifyFlagVal, "verify", false, "verify that running generators does not change the current output")
+
+	rootCmd.AddCommand(
+		commoncmd.NewInitCmd(&projectDirFlagVal, &cfgFlagVal),
+		commoncmd.NewLintCmd(&projectDirFlagVal, &cfgFlagVal),
+		commoncmd.NewCoverageCmdWithOptions(&projectDirFlagVal, &cfgFlagVal, cmdOptions),
+		commoncmd.NewOrphansCmdWithOptions(&projectDirFlagVal, &cfgFlagVal, cmdOptions),
+		commoncmd.NewListCmdWithOptions(&projectDirFlagVal, &cfgFlagVal, cmdOptions),
+		commoncmd.NewGraphCmdWithOptions(&projectDirFlagVal, &cfgFlagVal, cmdOptions),
+		commoncmd.NewWatchCmdWithOptions(&projectDirFlagVal, &cfgFlagVal, cmdOptions),
+		commoncmd.NewConfigCmd(),
+	)
 }
@@ -0,0 +1,49 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commoncmd
+
+import (
+	"fmt"
+
+	"github.com/palantir/go-generate/gogenerate"
+	"github.com/spf13/cobra"
+)
+
+// NewCoverageCmd returns the command with the default Options.
+func NewCoverageCmd(projectDirFlagVal, cfgFlagVal *string) *cobra.Command {
+	return NewCoverageCmdWithOptions(projectDirFlagVal, cfgFlagVal, Options{})
+}
+
+func NewCoverageCmdWithOptions(projectDirFlagVal, cfgFlagVal *string, opts Options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "coverage",
+		Short: "Report go:generate directives that are not run by any generator",
+		Long: `Reports the file, line and command of every go:generate directive in the project that is not in the
+go-generate-dir of any generator. Directories and files matched by the coverage-ignore configuration are skipped.
+Exits with a non-zero exit code if any directive is not run by a generator.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectParam, err := loadConfig(*projectDirFlagVal, *cfgFlagVal, opts.requireConfig())
+			if err != nil {
+				return err
+			}
+			if ok, err := gogenerate.CheckCoverage(*projectDirFlagVal, projectParam, cmd.OutOrStdout()); err != nil {
+				return err
+			} else if !ok {
+				return fmt.Errorf("")
+			}
+			return nil
+		},
+	}
+}
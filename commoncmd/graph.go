@@ -0,0 +1,53 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commoncmd
+
+import (
+	"github.com/palantir/go-generate/gogenerate"
+	"github.com/spf13/cobra"
+)
+
+// NewGraphCmd returns the command with the default Options.
+func NewGraphCmd(projectDirFlagVal, cfgFlagVal *string) *cobra.Command {
+	return NewGraphCmdWithOptions(projectDirFlagVal, cfgFlagVal, Options{})
+}
+
+func NewGraphCmdWithOptions(projectDirFlagVal, cfgFlagVal *string, opts Options) *cobra.Command {
+	var (
+		formatFlagVal    string
+		showDirsFlagVal  bool
+		showRootsFlagVal bool
+	)
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Print the generator dependency graph",
+		Long: `Prints the graph of the configured generators in the DOT language (the default) or as a Mermaid flowchart.
+Dependencies declared using depends-on are drawn as solid edges. If a generator currently produces a path that is an
+input of another generator (a path in its go-generate-dir or a path matched by its inputs) and no dependency is declared
+between them, the relationship is drawn as a dashed edge. Specify --show-dirs and --show-roots to also draw the
+go-generate-dir and the gen-paths roots of every generator.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectParam, err := loadConfig(*projectDirFlagVal, *cfgFlagVal, opts.requireConfig())
+			if err != nil {
+				return err
+			}
+			return gogenerate.WriteGraph(*projectDirFlagVal, projectParam, gogenerate.GraphFormat(formatFlagVal), showDirsFlagVal, showRootsFlagVal, cmd.OutOrStdout())
+		},
+	}
+	cmd.Flags().StringVar(&formatFlagVal, "format", string(gogenerate.GraphFormatDOT), `output format: "dot" or "mermaid"`)
+	cmd.Flags().BoolVar(&showDirsFlagVal, "show-dirs", false, "draw the go-generate-dir of every generator")
+	cmd.Flags().BoolVar(&showRootsFlagVal, "show-roots", false, "draw the directories beneath which the gen-paths of every generator reside")
+	return cmd
+}
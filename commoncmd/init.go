@@ -0,0 +1,88 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commoncmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/palantir/go-generate/gogenerate"
+	"github.com/palantir/go-generate/gogenerate/config"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+func NewInitCmd(projectDirFlagVal, cfgFlagVal *string) *cobra.Command {
+	var writeFlagVal bool
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Propose a configuration based on the go:generate directives in the project",
+		Long: `Finds the go:generate directives in the Go files of the project and prints a proposed configuration with one
+generator for every directory that contains directives. Files that are excluded by build constraints and the "vendor"
+and "testdata" directories are skipped. The gen-paths of directives that invoke stringer, mockgen or protoc are
+inferred from their arguments; the gen-paths of all other generators must be specified manually.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			directives, err := gogenerate.FindDirectives(*projectDirFlagVal, nil)
+			if err != nil {
+				return err
+			}
+			if len(directives) == 0 {
+				return errors.Errorf("no go:generate directives found in project")
+			}
+			cfg := config.ProposeConfig(directives)
+			cfgYML, err := yaml.Marshal(cfg)
+			if err != nil {
+				return errors.Wrapf(err, "failed to marshal configuration")
+			}
+
+			buf := &bytes.Buffer{}
+			_, _ = fmt.Fprintf(buf, "# Proposed from the %d go:generate directives in the project\n", len(directives))
+			var uninferred []string
+			for k, v := range cfg.Generators {
+				if len(v.GenPaths.Paths) == 0 {
+					uninferred = append(uninferred, k)
+				}
+			}
+			if len(uninferred) > 0 {
+				sort.Strings(uninferred)
+				_, _ = fmt.Fprintf(buf, "# gen-paths could not be inferred and must be specified for generators: %v\n", uninferred)
+			}
+			buf.Write(cfgYML)
+
+			if !writeFlagVal {
+				_, _ = cmd.OutOrStdout().Write(buf.Bytes())
+				return nil
+			}
+			if *cfgFlagVal == "" {
+				return errors.Errorf("--write requires --config to specify the file to write")
+			}
+			if _, err := os.Stat(*cfgFlagVal); err == nil {
+				return errors.Errorf("configuration file %s already exists", *cfgFlagVal)
+			} else if !os.IsNotExist(err) {
+				return errors.Wrapf(err, "failed to stat %s", *cfgFlagVal)
+			}
+			if err := os.WriteFile(*cfgFlagVal, buf.Bytes(), 0644); err != nil {
+				return errors.Wrapf(err, "failed to write %s", *cfgFlagVal)
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Wrote configuration with %d generator(s) to %s\n", len(cfg.Generators), *cfgFlagVal)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&writeFlagVal, "write", false, "write the proposed configuration to the file specified by --config (which must not exist) rather than printing it")
+	return cmd
+}
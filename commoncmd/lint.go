@@ -0,0 +1,59 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commoncmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/palantir/go-generate/gogenerate/config"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func NewLintCmd(projectDirFlagVal, cfgFlagVal *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "lint",
+		Short: "Report problems with the configuration without running generators",
+		Long: `Reports generators whose go-generate-dir does not exist or does not contain any go:generate directives,
+gen-paths that do not match any existing path and dependencies on generators that do not exist. Every problem is
+reported with the line of the configuration file or configuration fragment that caused it. Exits with a non-zero exit code if the configuration
+cannot be read or has any problems.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if *cfgFlagVal == "" {
+				return errors.Errorf("--config must be specified")
+			}
+			cfgYML, err := os.ReadFile(*cfgFlagVal)
+			if err != nil {
+				return errors.Wrapf(err, "failed to read file %s", *cfgFlagVal)
+			}
+			if _, err := config.LoadConfig(*cfgFlagVal, cfgYML); err != nil {
+				return err
+			}
+			problems, err := config.Lint(*projectDirFlagVal, *cfgFlagVal, cfgYML)
+			if err != nil {
+				return err
+			}
+			if len(problems) == 0 {
+				return nil
+			}
+			for _, problem := range problems {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", problem.Location(*cfgFlagVal), problem)
+			}
+			// problems have already been written, so return empty error to signal failure without additional output
+			return fmt.Errorf("")
+		},
+	}
+}
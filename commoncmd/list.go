@@ -0,0 +1,45 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commoncmd
+
+import (
+	"github.com/palantir/go-generate/gogenerate"
+	"github.com/spf13/cobra"
+)
+
+// NewListCmd returns the command with the default Options.
+func NewListCmd(projectDirFlagVal, cfgFlagVal *string) *cobra.Command {
+	return NewListCmdWithOptions(projectDirFlagVal, cfgFlagVal, Options{})
+}
+
+func NewListCmdWithOptions(projectDirFlagVal, cfgFlagVal *string, opts Options) *cobra.Command {
+	var formatFlagVal string
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "Describe the configured generators",
+		Long: `Prints the name, directory and environment variable names of every configured generator along with the
+go:generate directives in its directory and the paths that are currently matched by its gen-paths. The output is a
+table by default; specify --format=json to print a JSON array that can be consumed by scripts.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectParam, err := loadConfig(*projectDirFlagVal, *cfgFlagVal, opts.requireConfig())
+			if err != nil {
+				return err
+			}
+			return gogenerate.ListGenerators(*projectDirFlagVal, projectParam, gogenerate.ListFormat(formatFlagVal), cmd.OutOrStdout())
+		},
+	}
+	cmd.Flags().StringVar(&formatFlagVal, "format", string(gogenerate.ListFormatTable), `output format: "table" or "json"`)
+	return cmd
+}
@@ -0,0 +1,27 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commoncmd
+
+// Options specifies the optional behavior of the commands created by the "WithOptions" constructors of this package.
+// The zero value is the behavior of the commands created by the other constructors.
+type Options struct {
+	// RequireConfigFlagVal is the value of the flag that specifies whether it is an error for no configuration file to be
+	// specified. If nil or false, commands run with an empty configuration if no configuration file is specified.
+	RequireConfigFlagVal *bool
+}
+
+func (o Options) requireConfig() bool {
+	return o.RequireConfigFlagVal != nil && *o.RequireConfigFlagVal
+}
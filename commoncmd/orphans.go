@@ -0,0 +1,53 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commoncmd
+
+import (
+	"fmt"
+
+	"github.com/palantir/go-generate/gogenerate"
+	"github.com/spf13/cobra"
+)
+
+// NewOrphansCmd returns the command with the default Options.
+func NewOrphansCmd(projectDirFlagVal, cfgFlagVal *string) *cobra.Command {
+	return NewOrphansCmdWithOptions(projectDirFlagVal, cfgFlagVal, Options{})
+}
+
+func NewOrphansCmdWithOptions(projectDirFlagVal, cfgFlagVal *string, opts Options) *cobra.Command {
+	var pruneOrphansFlagVal bool
+	cmd := &cobra.Command{
+		Use:   "orphans",
+		Short: "Report generated Go files that are not matched by the gen-paths of any generator",
+		Long: `Reports the path and header line of every Go file in the project that carries the standard
+"// Code generated ... DO NOT EDIT." header but is not matched by the gen-paths of any generator. Such files are either
+left over from generators that were removed or are not verified by any generator. Exits with a non-zero exit code if
+any orphaned files are found unless --prune-orphans is specified, in which case the orphaned files are removed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectParam, err := loadConfig(*projectDirFlagVal, *cfgFlagVal, opts.requireConfig())
+			if err != nil {
+				return err
+			}
+			if ok, err := gogenerate.CheckOrphans(*projectDirFlagVal, projectParam, pruneOrphansFlagVal, cmd.OutOrStdout()); err != nil {
+				return err
+			} else if !ok {
+				return fmt.Errorf("")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&pruneOrphansFlagVal, "prune-orphans", false, "remove orphaned generated files rather than reporting them as a failure")
+	return cmd
+}
@@ -16,56 +16,213 @@ package commoncmd
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"time"
 
 	"github.com/palantir/go-generate/gogenerate"
 	"github.com/palantir/go-generate/gogenerate/config"
+	"github.com/palantir/pkg/matcher"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v2"
 )
 
+// NewRunCmd returns the command with the default Options.
 func NewRunCmd(use string, projectDirFlagVal, cfgFlagVal *string, verifyFlagVal *bool) *cobra.Command {
-	return &cobra.Command{
+	return NewRunCmdWithOptions(use, projectDirFlagVal, cfgFlagVal, verifyFlagVal, Options{})
+}
+
+func NewRunCmdWithOptions(use string, projectDirFlagVal, cfgFlagVal *string, verifyFlagVal *bool, opts Options) *cobra.Command {
+	var (
+		checkDeterminismFlagVal bool
+		cleanOutputsFlagVal     bool
+		shuffleFlagVal          int64
+		paranoidFlagVal         bool
+		requireCoverageFlagVal  bool
+		compileCheckFlagVal     string
+		compileCheckOnceFlagVal bool
+		sinceFlagVal            string
+		changedFilesFromFlagVal string
+		sinceAllOnFlagVal       []string
+		dryRunFlagVal           bool
+	)
+	cmd := &cobra.Command{
 		Use:   use,
 		Short: "Run generators specified in configuration",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			projectParam, err := loadConfig(*cfgFlagVal)
+			if cleanOutputsFlagVal && !checkDeterminismFlagVal {
+				return errors.Errorf("--clean-outputs can only be specified with --check-determinism")
+			}
+			if requireCoverageFlagVal && !*verifyFlagVal {
+				return errors.Errorf("--require-coverage can only be specified with --verify")
+			}
+			shuffle := cmd.Flags().Changed(shuffleFlagName)
+			if countTrue(*verifyFlagVal, checkDeterminismFlagVal, shuffle) > 1 {
+				return errors.Errorf("at most one of --verify, --check-determinism and --shuffle can be specified")
+			}
+
+			compileCheck := cmd.Flags().Changed(compileCheckFlagName)
+			if compileCheck && (checkDeterminismFlagVal || shuffle) {
+				return errors.Errorf("--compile-check cannot be specified with --check-determinism or --shuffle")
+			}
+			if compileCheckOnceFlagVal && !compileCheck {
+				return errors.Errorf("--compile-check-once can only be specified with --compile-check")
+			}
+
+			if sinceFlagVal != "" && changedFilesFromFlagVal != "" {
+				return errors.Errorf("at most one of --since and --changed-files-from can be specified")
+			}
+			if len(sinceAllOnFlagVal) > 0 && sinceFlagVal == "" && changedFilesFromFlagVal == "" {
+				return errors.Errorf("--since-all-on can only be specified with --since or --changed-files-from")
+			}
+
+			if dryRunFlagVal && (countTrue(*verifyFlagVal, checkDeterminismFlagVal, shuffle, requireCoverageFlagVal) > 0 || compileCheck) {
+				return errors.Errorf("--dry-run cannot be specified with --verify, --check-determinism, --shuffle, --require-coverage or --compile-check")
+			}
+
+			projectParam, err := loadConfig(*projectDirFlagVal, *cfgFlagVal, opts.requireConfig())
 			if err != nil {
 				return err
 			}
-			if *verifyFlagVal {
-				if ok, err := gogenerate.Verify(*projectDirFlagVal, projectParam, cmd.OutOrStdout()); err != nil {
+			if sinceFlagVal != "" || changedFilesFromFlagVal != "" {
+				var changedPaths []string
+				if sinceFlagVal != "" {
+					changedPaths, err = gogenerate.ChangedFilesSince(*projectDirFlagVal, sinceFlagVal)
+				} else {
+					changedPaths, err = readChangedFiles(changedFilesFromFlagVal, cmd.InOrStdin())
+				}
+				if err != nil {
+					return err
+				}
+				var forceAll matcher.Matcher
+				if len(sinceAllOnFlagVal) > 0 {
+					forceAll = matcher.Path(sinceAllOnFlagVal...)
+				}
+				allCount := len(projectParam.Generators)
+				projectParam = gogenerate.AffectedParam(projectParam, changedPaths, forceAll)
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Running %d of %d generators affected by %d changed files: %v\n", len(projectParam.Generators), allCount, len(changedPaths), projectParam.Generators.SortedKeys())
+			}
+			if dryRunFlagVal {
+				return gogenerate.DryRun(*projectDirFlagVal, projectParam, cmd.OutOrStdout())
+			}
+			var opts []gogenerate.Option
+			if paranoidFlagVal {
+				opts = append(opts, gogenerate.Paranoid())
+			}
+			if compileCheck {
+				switch tool := gogenerate.CompileCheckTool(compileCheckFlagVal); tool {
+				case gogenerate.CompileCheckBuild, gogenerate.CompileCheckVet:
+					opts = append(opts, gogenerate.CompileCheck(tool, compileCheckOnceFlagVal))
+				default:
+					return errors.Errorf(`invalid --compile-check tool %q: must be one of "build" or "vet"`, compileCheckFlagVal)
+				}
+			}
+			if shuffle {
+				seed := shuffleFlagVal
+				if seed == 0 {
+					seed = time.Now().UnixNano()
+				}
+				if ok, err := gogenerate.CheckOrderIndependence(*projectDirFlagVal, projectParam, seed, cmd.OutOrStdout(), opts...); err != nil {
 					return err
 				} else if !ok {
-					// if verification failed, return empty error -- the "Verify" call itself will have already written
+					return fmt.Errorf("")
+				}
+				return nil
+			}
+			if checkDeterminismFlagVal {
+				if ok, err := gogenerate.CheckDeterminism(*projectDirFlagVal, projectParam, cleanOutputsFlagVal, cmd.OutOrStdout(), opts...); err != nil {
+					return err
+				} else if !ok {
+					return fmt.Errorf("")
+				}
+				return nil
+			}
+			if *verifyFlagVal {
+				ok, err := gogenerate.Verify(*projectDirFlagVal, projectParam, cmd.OutOrStdout(), opts...)
+				if err != nil {
+					return err
+				}
+				if requireCoverageFlagVal {
+					covered, err := gogenerate.CheckCoverage(*projectDirFlagVal, projectParam, cmd.OutOrStdout())
+					if err != nil {
+						return err
+					}
+					ok = ok && covered
+				}
+				if !ok {
+					// if verification failed, return empty error -- the "Verify" and "CheckCoverage" calls will have already written
 					// the output to stdout and returning an empty error signals to handlers that no other output needs
 					// to be printed.
 					return fmt.Errorf("")
 				}
 				return nil
 			}
-			return gogenerate.Run(*projectDirFlagVal, projectParam, cmd.OutOrStdout())
+			return gogenerate.Run(*projectDirFlagVal, projectParam, cmd.OutOrStdout(), opts...)
 		},
 	}
+	cmd.Flags().BoolVar(&checkDeterminismFlagVal, "check-determinism", false, "run every generator twice and verify that the second run produces the same output as the first")
+	cmd.Flags().BoolVar(&cleanOutputsFlagVal, "clean-outputs", false, "remove the outputs of a generator before running it for the second time (requires --check-determinism)")
+	cmd.Flags().Int64Var(&shuffleFlagVal, shuffleFlagName, 0, "run the generators in sorted order and then in a shuffled order that honors declared dependencies and verify that both runs produce the same output. The optional value is the seed used to shuffle; if it is 0 or omitted, a random seed is used")
+	cmd.Flags().Lookup(shuffleFlagName).NoOptDefVal = "0"
+	cmd.Flags().BoolVar(&requireCoverageFlagVal, "require-coverage", false, "also verify that every go:generate directive in the project is run by a generator (requires --verify)")
+	cmd.Flags().StringVar(&compileCheckFlagVal, compileCheckFlagName, "", `after running each generator, check that the packages that contain the Go files matched by its gen-paths compile using "go build" or, if the value is "vet", "go vet"`)
+	cmd.Flags().Lookup(compileCheckFlagName).NoOptDefVal = string(gogenerate.CompileCheckBuild)
+	cmd.Flags().BoolVar(&compileCheckOnceFlagVal, "compile-check-once", false, "check the packages of all generators once after all generators are run rather than after each generator (requires --compile-check)")
+	cmd.Flags().StringVar(&sinceFlagVal, "since", "", "only run the generators affected by the files that changed since the merge base of the provided git ref and HEAD (including uncommitted and untracked files)")
+	cmd.Flags().StringVar(&changedFilesFromFlagVal, "changed-files-from", "", `only run the generators affected by the changed files listed one per line in the provided file ("-" reads from stdin)`)
+	cmd.Flags().StringSliceVar(&sinceAllOnFlagVal, "since-all-on", nil, "path patterns that cause all generators to be run if any changed file matches them (for example, go.mod)")
+	cmd.Flags().BoolVar(&dryRunFlagVal, "dry-run", false, "print the command, directory, environment, go:generate commands and matched paths of every generator without running any generators")
+	cmd.Flags().BoolVar(&paranoidFlagVal, "paranoid", false, "hash the content of every matched file on every scan rather than reusing the checksums of files whose size, modification time, inode and mode did not change")
+	return cmd
 }
 
-func loadConfig(cfgFile string) (gogenerate.ProjectParam, error) {
-	cfgYML, err := os.ReadFile(cfgFile)
-	if os.IsNotExist(err) {
+const (
+	shuffleFlagName      = "shuffle"
+	compileCheckFlagName = "compile-check"
+)
+
+func countTrue(vals ...bool) int {
+	count := 0
+	for _, v := range vals {
+		if v {
+			count++
+		}
+	}
+	return count
+}
+
+func readChangedFiles(changedFilesFrom string, stdin io.Reader) ([]string, error) {
+	if changedFilesFrom == "-" {
+		return gogenerate.ReadChangedFiles(stdin)
+	}
+	f, err := os.Open(changedFilesFrom)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %s", changedFilesFrom)
+	}
+	defer func() {
+		// file is opened for reading only, so safe to ignore errors on close
+		_ = f.Close()
+	}()
+	return gogenerate.ReadChangedFiles(f)
+}
+
+// loadConfig returns the parameters specified by the provided configuration file. Returns an error if the file does not
+// exist. If no file is specified, the parameters of an empty configuration are returned unless requireConfig is true, in
+// which case an error is returned instead.
+func loadConfig(projectDir, cfgFile string, requireConfig bool) (gogenerate.ProjectParam, error) {
+	if cfgFile == "" {
+		if requireConfig {
+			return gogenerate.ProjectParam{}, errors.Errorf("--config must be specified when --require-config is specified")
+		}
 		return gogenerate.ProjectParam{}, nil
 	}
+	cfgYML, err := os.ReadFile(cfgFile)
 	if err != nil {
 		return gogenerate.ProjectParam{}, errors.Wrapf(err, "failed to read file %s", cfgFile)
 	}
-	upgradedCfg, err := config.UpgradeConfig(cfgYML)
+	cfg, err := config.LoadConfig(cfgFile, cfgYML)
 	if err != nil {
 		return gogenerate.ProjectParam{}, err
 	}
-
-	var cfg config.ProjectConfig
-	if err := yaml.Unmarshal(upgradedCfg, &cfg); err != nil {
-		return gogenerate.ProjectParam{}, errors.Wrapf(err, "failed to unmarshal go-generate configuration")
-	}
-	return cfg.ToParam(), nil
+	return cfg.ToParamWithFragments(projectDir, cfgFile)
 }
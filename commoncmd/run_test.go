@@ -0,0 +1,100 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commoncmd_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/palantir/go-generate/commoncmd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	existingCfg := filepath.Join(tmpDir, "generate.yml")
+	err = os.WriteFile(existingCfg, []byte(`version: 1
+generators:
+  foo:
+    go-generate-dir: foo
+`), 0644)
+	require.NoError(t, err)
+	missingCfg := filepath.Join(tmpDir, "missing.yml")
+
+	for currCaseNum, currCase := range []struct {
+		name          string
+		cfgFile       string
+		requireConfig bool
+		wantNames     []string
+		wantErr       string
+	}{
+		{
+			name:      "configuration file is loaded",
+			cfgFile:   existingCfg,
+			wantNames: []string{"foo"},
+		},
+		{
+			name:      "configuration is empty if file is not specified",
+			wantNames: []string{},
+		},
+		{
+			name:    "file must exist if it is specified",
+			cfgFile: missingCfg,
+			wantErr: "failed to read file " + missingCfg + ": open " + missingCfg + ": no such file or directory",
+		},
+		{
+			name:          "file must be specified if configuration is required",
+			requireConfig: true,
+			wantErr:       "--config must be specified when --require-config is specified",
+		},
+		{
+			name:          "file must exist if configuration is required",
+			cfgFile:       missingCfg,
+			requireConfig: true,
+			wantErr:       "failed to read file " + missingCfg + ": open " + missingCfg + ": no such file or directory",
+		},
+	} {
+		projectDir, cfgFile, requireConfig := tmpDir, currCase.cfgFile, currCase.requireConfig
+		cmd := commoncmd.NewListCmdWithOptions(&projectDir, &cfgFile, commoncmd.Options{RequireConfigFlagVal: &requireConfig})
+		cmd.SetArgs([]string{"--format", "json"})
+		buf := &bytes.Buffer{}
+		cmd.SetOut(buf)
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+		err := cmd.Execute()
+		if currCase.wantErr != "" {
+			assert.EqualError(t, err, currCase.wantErr, "Case %d: %s", currCaseNum, currCase.name)
+			continue
+		}
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		var generators []struct {
+			Name string `json:"name"`
+		}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &generators), "Case %d: %s", currCaseNum, currCase.name)
+		names := []string{}
+		for _, generator := range generators {
+			names = append(names, generator.Name)
+		}
+		assert.Equal(t, currCase.wantNames, names, "Case %d: %s", currCaseNum, currCase.name)
+	}
+}
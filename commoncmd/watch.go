@@ -0,0 +1,64 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commoncmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/palantir/go-generate/gogenerate"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewWatchCmd returns the command with the default Options.
+func NewWatchCmd(projectDirFlagVal, cfgFlagVal *string) *cobra.Command {
+	return NewWatchCmdWithOptions(projectDirFlagVal, cfgFlagVal, Options{})
+}
+
+func NewWatchCmdWithOptions(projectDirFlagVal, cfgFlagVal *string, opts Options) *cobra.Command {
+	var (
+		intervalFlagVal time.Duration
+		debounceFlagVal time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Run generators whenever their inputs change",
+		Long: `Polls the project for changes and runs the generators that are affected by them. A generator is affected by
+a change to a file in its go-generate-dir or to a path matched by its inputs configuration, and all of the generators
+that depend on an affected generator are also run. Changes to paths matched by gen-paths are ignored. Runs until
+interrupted.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if intervalFlagVal <= 0 {
+				return errors.Errorf("--interval must be positive, was %v", intervalFlagVal)
+			}
+			if debounceFlagVal < 0 {
+				return errors.Errorf("--debounce must not be negative, was %v", debounceFlagVal)
+			}
+			projectParam, err := loadConfig(*projectDirFlagVal, *cfgFlagVal, opts.requireConfig())
+			if err != nil {
+				return err
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			return gogenerate.Watch(ctx, *projectDirFlagVal, projectParam, intervalFlagVal, debounceFlagVal, cmd.OutOrStdout())
+		},
+	}
+	cmd.Flags().DurationVar(&intervalFlagVal, "interval", 500*time.Millisecond, "the interval at which the project is polled for changes")
+	cmd.Flags().DurationVar(&debounceFlagVal, "debounce", 300*time.Millisecond, "the period without further changes that must elapse after a change before generators are run")
+	return cmd
+}
@@ -0,0 +1,48 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commoncmd_test
+
+import (
+	"testing"
+
+	"github.com/palantir/go-generate/commoncmd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchInvalidDurations(t *testing.T) {
+	for currCaseNum, currCase := range []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{
+			name:    "interval must be positive",
+			args:    []string{"--interval", "0s"},
+			wantErr: "--interval must be positive, was 0s",
+		},
+		{
+			name:    "debounce must not be negative",
+			args:    []string{"--debounce", "-1s"},
+			wantErr: "--debounce must not be negative, was -1s",
+		},
+	} {
+		projectDir, cfgFile := ".", ""
+		cmd := commoncmd.NewWatchCmd(&projectDir, &cfgFile)
+		cmd.SetArgs(currCase.args)
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+		assert.EqualError(t, cmd.Execute(), currCase.wantErr, "Case %d: %s", currCaseNum, currCase.name)
+	}
+}
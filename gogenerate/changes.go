@@ -0,0 +1,109 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/palantir/pkg/matcher"
+	"github.com/pkg/errors"
+)
+
+// ChangedFilesSince returns the sorted slash-separated paths relative to the project directory of the files in the
+// project that changed since the merge base of the provided git ref and HEAD. Changes to tracked files that are not
+// committed and untracked files that are not ignored are also considered to be changes. The git CLI must be available
+// and the project directory must be in a git repository.
+func ChangedFilesSince(rootDir, ref string) ([]string, error) {
+	mergeBase, err := git(rootDir, "merge-base", ref, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	// paths are separated by NUL so that paths that contain newlines or other special characters are not quoted
+	diffOutput, err := git(rootDir, "diff", "--name-only", "-z", "--no-renames", "--relative", strings.TrimSpace(mergeBase))
+	if err != nil {
+		return nil, err
+	}
+	untrackedOutput, err := git(rootDir, "ls-files", "-z", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	return sortedPaths(strings.Split(diffOutput+"\x00"+untrackedOutput, "\x00")), nil
+}
+
+// ReadChangedFiles reads the paths of changed files relative to the project directory from the provided reader, which
+// should provide one path per line. Empty lines are ignored. Returns the sorted and deduplicated slash-separated paths.
+func ReadChangedFiles(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimSpace(scanner.Text()))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed to read changed files")
+	}
+	return sortedPaths(lines), nil
+}
+
+// sortedPaths returns the sorted and deduplicated clean slash-separated forms of the provided paths. Empty paths are
+// ignored.
+func sortedPaths(paths []string) []string {
+	pathSet := make(map[string]struct{})
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		pathSet[path.Clean(filepath.ToSlash(p))] = struct{}{}
+	}
+	var sorted []string
+	for p := range pathSet {
+		sorted = append(sorted, p)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// AffectedParam returns the parameters for running only the generators that are affected by changes to the provided
+// paths (as determined by Generators.Affected). If any of the changed paths is matched by the provided forceAll
+// matcher (which may be nil), the provided parameters are returned unmodified so that all generators are run.
+func AffectedParam(projectParam ProjectParam, changedPaths []string, forceAll matcher.Matcher) ProjectParam {
+	if forceAll != nil {
+		for _, changedPath := range changedPaths {
+			if forceAll.Match(changedPath) {
+				return projectParam
+			}
+		}
+	}
+	projectParam.Generators = projectParam.Generators.Subset(projectParam.Generators.Affected(changedPaths))
+	return projectParam
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to run git %s: %s", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
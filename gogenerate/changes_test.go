@@ -0,0 +1,127 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate_test
+
+import (
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/palantir/go-generate/gogenerate"
+	"github.com/palantir/pkg/matcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangedFilesSince(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	runGit := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = tmpDir
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	writeFile := func(relPath, content string) {
+		err := os.MkdirAll(path.Join(tmpDir, path.Dir(relPath)), 0755)
+		require.NoError(t, err)
+		err = os.WriteFile(path.Join(tmpDir, relPath), []byte(content), 0644)
+		require.NoError(t, err)
+	}
+
+	runGit("init", "-q", "-b", "main")
+	writeFile("project/unchanged.txt", "unchanged")
+	writeFile("project/committed.txt", "committed")
+	writeFile("project/modified.txt", "modified")
+	writeFile("project/renamed.txt", "renamed")
+	writeFile("other/other.txt", "other")
+	writeFile(".gitignore", "*.log\n")
+	runGit("add", ".")
+	runGit("commit", "-q", "-m", "initial")
+	runGit("checkout", "-q", "-b", "feature")
+	writeFile("project/committed.txt", "committed on branch")
+	writeFile("other/other.txt", "other on branch")
+	runGit("mv", "project/renamed.txt", "project/moved.txt")
+	writeFile("project/données.txt", "added on branch")
+	runGit("add", "project/données.txt")
+	runGit("commit", "-q", "-a", "-m", "branch")
+	writeFile("project/modified.txt", "modified in working tree")
+	writeFile("project/untracked.txt", "untracked")
+	writeFile("project/untracked café.txt", "untracked")
+	writeFile("project/\"quoted\".txt", "untracked")
+	writeFile("project/ignored.log", "ignored")
+
+	got, err := gogenerate.ChangedFilesSince(path.Join(tmpDir, "project"), "main")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`"quoted".txt`,
+		"committed.txt",
+		"données.txt",
+		"modified.txt",
+		"moved.txt",
+		"renamed.txt",
+		"untracked café.txt",
+		"untracked.txt",
+	}, got)
+
+	_, err = gogenerate.ChangedFilesSince(path.Join(tmpDir, "project"), "missing")
+	assert.Error(t, err)
+}
+
+func TestReadChangedFiles(t *testing.T) {
+	got, err := gogenerate.ReadChangedFiles(strings.NewReader("foo/bar.go\n\n./baz.go\nfoo/bar.go\n  qux/../quux.go  \n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"baz.go", "foo/bar.go", "quux.go"}, got)
+}
+
+func TestAffectedParam(t *testing.T) {
+	projectParam := gogenerate.ProjectParam{
+		Generators: gogenerate.Generators{
+			"proto": {GoGenDir: "proto"},
+			"mocks": {GoGenDir: "mocks", DependsOn: []string{"proto"}},
+			"other": {GoGenDir: "other"},
+		},
+	}
+	for currCaseNum, currCase := range []struct {
+		name         string
+		changedPaths []string
+		forceAll     matcher.Matcher
+		want         []string
+	}{
+		{
+			name:         "affected generators and dependents are selected",
+			changedPaths: []string{"proto/proto.go", "README.md"},
+			want:         []string{"mocks", "proto"},
+		},
+		{
+			name:         "no generators are selected if none are affected",
+			changedPaths: []string{"README.md"},
+		},
+		{
+			name:         "all generators are selected if a changed path matches force all",
+			changedPaths: []string{"proto/proto.go", "go.mod"},
+			forceAll:     matcher.Path("go.mod", "go.sum"),
+			want:         []string{"mocks", "other", "proto"},
+		},
+	} {
+		got := gogenerate.AffectedParam(projectParam, currCase.changedPaths, currCase.forceAll)
+		assert.Equal(t, currCase.want, got.Generators.SortedKeys(), "Case %d: %s", currCaseNum, currCase.name)
+	}
+}
@@ -0,0 +1,691 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/palantir/pkg/matcher"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+// racyGracePeriod is the amount of time before the start of a scan during which a file modification may not be
+// reflected in the file's modification time because of the timestamp granularity of the file system. The checksums of
+// files modified during this period are always recomputed by the next scan.
+const racyGracePeriod = 2 * time.Second
+
+type checksumSet map[string]*fileChecksumInfo
+
+type ChecksumsDiff map[string]string
+
+func (c ChecksumsDiff) String() string {
+	var parts []string
+	for _, k := range c.sortedKeys() {
+		parts = append(parts, fmt.Sprintf("%s: %s", k, c[k]))
+	}
+	return strings.Join(parts, "\n")
+}
+
+func (c ChecksumsDiff) sortedKeys() []string {
+	var sortedKeys []string
+	for k := range c {
+		sortedKeys = append(sortedKeys, k)
+	}
+	sort.Strings(sortedKeys)
+	return sortedKeys
+}
+
+// forGenerator returns the subset of the checksums whose paths are matched by the gen-paths of the provided generator.
+// If the generator has a normalizer, the checksums of files in the returned set are the checksums of their normalized
+// content.
+func (c checksumSet) forGenerator(name string, param GeneratorParam) checksumSet {
+	filtered := make(checksumSet)
+	for k, v := range c {
+		if !param.GenPaths.Match(k) {
+			continue
+		}
+		if normalized, ok := v.normalizedChecksums[name]; ok {
+			normalizedV := *v
+			normalizedV.sha256checksum = normalized
+			v = &normalizedV
+		}
+		filtered[k] = v
+	}
+	return filtered
+}
+
+// compare returns the differences between this set and the provided set. If ignoreMode is true, differences in the
+// permission bits of paths are not reported.
+func (c checksumSet) compare(other checksumSet, ignoreMode bool) ChecksumsDiff {
+	diffs := make(map[string]string)
+
+	// determine missing and extra entries
+	for k := range c {
+		if _, ok := other[k]; !ok {
+			diffs[k] = "existed before, no longer exists"
+		}
+	}
+	for k := range other {
+		if _, ok := c[k]; !ok {
+			diffs[k] = "did not exist before, now exists"
+		}
+	}
+
+	// compare content
+	for k, v := range c {
+		otherV, ok := other[k]
+		if !ok {
+			continue
+		}
+
+		if v.kind != otherV.kind {
+			diffs[k] = fmt.Sprintf("was previously a %s, is now a %s", v.kind, otherV.kind)
+			continue
+		}
+		if v.linkTarget != otherV.linkTarget {
+			diffs[k] = fmt.Sprintf("previously linked to %q, now links to %q", v.linkTarget, otherV.linkTarget)
+			continue
+		}
+		var parts []string
+		if v.sha256checksum != otherV.sha256checksum {
+			parts = append(parts, fmt.Sprintf("previously had checksum %s, now has checksum %s", v.sha256checksum, otherV.sha256checksum))
+		}
+		// permissions of symlinks are not meaningful
+		if !ignoreMode && v.kind != symlinkKind && v.mode.Perm() != otherV.mode.Perm() {
+			parts = append(parts, fmt.Sprintf("previously had mode %04o, now has mode %04o", v.mode.Perm(), otherV.mode.Perm()))
+		}
+		if len(parts) > 0 {
+			diffs[k] = strings.Join(parts, "; ")
+		}
+	}
+
+	return diffs
+}
+
+// contents returns the content of all of the regular files in the set. Directories and symlinks are omitted.
+func (c checksumSet) contents(rootDir string) (map[string][]byte, error) {
+	contents := make(map[string][]byte)
+	for k, v := range c {
+		if v.kind != fileKind {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(rootDir, k))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read file")
+		}
+		contents[k] = content
+	}
+	return contents, nil
+}
+
+// goFiles returns the paths of all of the regular Go files in the set.
+func (c checksumSet) goFiles() []string {
+	var goFiles []string
+	for k, v := range c {
+		if v.kind == fileKind && strings.HasSuffix(k, ".go") {
+			goFiles = append(goFiles, k)
+		}
+	}
+	return goFiles
+}
+
+// remove removes all of the paths in the set from the file system.
+func (c checksumSet) remove(rootDir string) error {
+	var sortedKeys []string
+	for k := range c {
+		sortedKeys = append(sortedKeys, k)
+	}
+	sort.Strings(sortedKeys)
+
+	for _, k := range sortedKeys {
+		if err := os.RemoveAll(filepath.Join(rootDir, k)); err != nil {
+			return errors.Wrapf(err, "failed to remove %q", k)
+		}
+	}
+	return nil
+}
+
+// outputSnapshot records the content and modes of a set of paths so that they can be restored later.
+type outputSnapshot struct {
+	checksums checksumSet
+	contents  map[string][]byte
+	modes     map[string]os.FileMode
+}
+
+func newOutputSnapshot(rootDir string, s *scanner) (*outputSnapshot, error) {
+	checksums, err := s.scan()
+	if err != nil {
+		return nil, err
+	}
+	contents, err := checksums.contents(rootDir)
+	if err != nil {
+		return nil, err
+	}
+	modes := make(map[string]os.FileMode)
+	for k := range checksums {
+		fi, err := os.Lstat(filepath.Join(rootDir, k))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to stat %q", k)
+		}
+		modes[k] = fi.Mode().Perm()
+	}
+	return &outputSnapshot{
+		checksums: checksums,
+		contents:  contents,
+		modes:     modes,
+	}, nil
+}
+
+// restore recreates all of the paths in the snapshot. Paths that were created after the snapshot was taken are not
+// removed.
+func (s *outputSnapshot) restore(rootDir string) error {
+	var sortedKeys []string
+	for k := range s.checksums {
+		sortedKeys = append(sortedKeys, k)
+	}
+	sort.Strings(sortedKeys)
+
+	for _, k := range sortedKeys {
+		p := filepath.Join(rootDir, k)
+		if s.checksums[k].kind == dirKind {
+			if err := os.MkdirAll(p, s.modes[k]); err != nil {
+				return errors.Wrapf(err, "failed to restore directory %q", k)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return errors.Wrapf(err, "failed to create parent directory of %q", k)
+		}
+		if s.checksums[k].kind == symlinkKind {
+			if err := os.Symlink(s.checksums[k].linkTarget, p); err != nil {
+				return errors.Wrapf(err, "failed to restore symlink %q", k)
+			}
+			continue
+		}
+		if err := os.WriteFile(p, s.contents[k], s.modes[k]); err != nil {
+			return errors.Wrapf(err, "failed to restore file %q", k)
+		}
+	}
+	return nil
+}
+
+// pathKind is the kind of a path on the file system.
+type pathKind string
+
+const (
+	fileKind    pathKind = "file"
+	dirKind     pathKind = "directory"
+	symlinkKind pathKind = "symlink"
+)
+
+type fileChecksumInfo struct {
+	path string
+	kind pathKind
+	// target of the symlink if kind is symlinkKind
+	linkTarget string
+	// checksum of the content of the file if kind is fileKind
+	sha256checksum string
+	// checksums of the normalized content of the file keyed by the name of the generator whose normalizer was applied
+	normalizedChecksums map[string]string
+
+	// metadata of the path at the time the checksum was computed
+	size    int64
+	modTime time.Time
+	mode    os.FileMode
+	inode   uint64
+}
+
+// scanner computes the checksums of all of the paths matched by a set of generators. A scan only walks the directories
+// that can contain paths matched by the generators: if every "gen-paths" path pattern of the generators begins with a
+// literal directory prefix, only the directories with those prefixes are walked. Paths matched by the exclude matcher
+// are skipped, and excluded directories are not walked at all.
+type scanner struct {
+	rootDir string
+	// names and parameters of the generators whose paths are scanned
+	generators []string
+	params     Generators
+	include    matcher.Matcher
+	exclude    matcher.Matcher
+	// relative paths of the directories or files that are walked by a scan
+	roots []string
+	// if true, the checksums computed by previous scans are never reused
+	paranoid   bool
+	scanErrors ScanErrorPolicy
+	// writer to which warnings are written
+	stdout io.Writer
+
+	// checksums computed by the previous scan and the time at which it started
+	prev      checksumSet
+	prevStart time.Time
+}
+
+func newScanner(rootDir string, projectParam ProjectParam, o *options, stdout io.Writer, generators ...string) *scanner {
+	var include []matcher.Matcher
+	var roots []string
+	scanAll := false
+	for _, k := range generators {
+		g := projectParam.Generators[k]
+		include = append(include, g.GenPaths)
+		if g.GenPathRoots == nil {
+			scanAll = true
+		}
+		roots = append(roots, g.GenPathRoots...)
+	}
+	if scanAll {
+		roots = []string{"."}
+	}
+	return &scanner{
+		rootDir:    rootDir,
+		generators: generators,
+		params:     projectParam.Generators,
+		include:    matcher.Any(include...),
+		exclude:    projectParam.Exclude,
+		roots:      minimalRoots(roots),
+		paranoid:   o.paranoid,
+		scanErrors: projectParam.ScanErrors,
+		stdout:     stdout,
+	}
+}
+
+type matchedPath struct {
+	relPath string
+	path    string
+	info    os.FileInfo
+}
+
+type scanErrorClass string
+
+const (
+	permissionDenied scanErrorClass = "permission-denied"
+	notExist         scanErrorClass = "not-exist"
+	otherScanError   scanErrorClass = "other"
+)
+
+// scanError is an error encountered while scanning a path.
+type scanError struct {
+	relPath string
+	class   scanErrorClass
+	err     error
+}
+
+func newScanError(relPath string, err error) scanError {
+	class := otherScanError
+	switch {
+	case os.IsPermission(err):
+		class = permissionDenied
+	case os.IsNotExist(err):
+		class = notExist
+	}
+	return scanError{
+		relPath: relPath,
+		class:   class,
+		err:     err,
+	}
+}
+
+// scan returns the checksums of all of the matched paths. The content of the matched files is hashed in parallel, and
+// unless the scanner is paranoid, the checksum computed by the previous scan is reused for every file whose size,
+// modification time, inode and mode did not change since then. Errors encountered while scanning are handled based
+// on the scan error policy of the scanner, and paths for which errors are skipped or warned about are treated as if they
+// did not exist.
+func (s *scanner) scan() (checksumSet, error) {
+	start := time.Now()
+
+	var matched []matchedPath
+	var scanErrs []scanError
+	for _, root := range s.roots {
+		rootPath := filepath.Join(s.rootDir, root)
+		if _, err := os.Lstat(rootPath); os.IsNotExist(err) {
+			// root is not required to exist
+			continue
+		}
+		if err := filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
+			relPath, relErr := filepath.Rel(s.rootDir, path)
+			if relErr != nil {
+				return relErr
+			}
+			if err != nil {
+				// path could not be read: record the error if the path could contain matched paths and continue walking
+				if s.mayContainMatches(relPath) {
+					scanErrs = append(scanErrs, newScanError(relPath, err))
+				}
+				if d != nil && d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if s.exclude != nil && s.exclude.Match(relPath) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !s.include.Match(relPath) {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				scanErrs = append(scanErrs, newScanError(relPath, err))
+				return nil
+			}
+			matched = append(matched, matchedPath{
+				relPath: relPath,
+				path:    path,
+				info:    info,
+			})
+			return nil
+		}); err != nil {
+			return nil, errors.Wrapf(err, "failed to walk directory %q", rootPath)
+		}
+	}
+
+	// fill in the reused checksums before any workers are started so that the map is only written to concurrently
+	// under the lock
+	pathsToChecksums := make(map[string]*fileChecksumInfo)
+	var toHash []matchedPath
+	for _, m := range matched {
+		if prev := s.reusableChecksum(m.relPath, m.info); prev != nil {
+			pathsToChecksums[m.relPath] = prev
+			continue
+		}
+		toHash = append(toHash, m)
+	}
+
+	var mutex sync.Mutex
+	var g errgroup.Group
+	g.SetLimit(runtime.GOMAXPROCS(0))
+	for _, m := range toHash {
+		g.Go(func() error {
+			checksum, err := newChecksum(m.path, m.info, s.normalizers(m.relPath))
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				scanErrs = append(scanErrs, newScanError(m.relPath, err))
+				return nil
+			}
+			pathsToChecksums[m.relPath] = checksum
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	if err := s.handleScanErrors(scanErrs); err != nil {
+		return nil, err
+	}
+
+	s.prev = pathsToChecksums
+	s.prevStart = start
+	return pathsToChecksums, nil
+}
+
+// handleScanErrors applies the scan error policy to the provided errors. Returns an error for the first error (in
+// order of path) whose action is to fail and writes a warning for every error whose action is to warn.
+func (s *scanner) handleScanErrors(scanErrs []scanError) error {
+	sort.Slice(scanErrs, func(i, j int) bool {
+		return scanErrs[i].relPath < scanErrs[j].relPath
+	})
+	var warnings []string
+	for _, scanErr := range scanErrs {
+		action := ScanErrorFail
+		switch scanErr.class {
+		case permissionDenied:
+			action = s.scanErrors.PermissionDenied
+		case notExist:
+			action = s.scanErrors.NotExist
+		}
+
+		msg := fmt.Sprintf("%s: %q (%s)", formatGeneratorNames(s.owners(scanErr.relPath)), scanErr.relPath, scanErr.class)
+		switch action {
+		case ScanErrorSkip:
+			continue
+		case ScanErrorWarn:
+			warnings = append(warnings, fmt.Sprintf("Warning: %s: skipping path: %v", msg, scanErr.err))
+		default:
+			return errors.Wrapf(scanErr.err, "%s", msg)
+		}
+	}
+	for _, warning := range warnings {
+		_, _ = fmt.Fprintln(s.stdout, warning)
+	}
+	return nil
+}
+
+// normalizers returns the normalizers of the generators whose gen-paths match the provided path keyed by the name of
+// the generator.
+func (s *scanner) normalizers(relPath string) map[string]Normalizer {
+	var normalizers map[string]Normalizer
+	for _, k := range s.generators {
+		param := s.params[k]
+		if param.Normalize.IsZero() || !param.GenPaths.Match(relPath) {
+			continue
+		}
+		if normalizers == nil {
+			normalizers = make(map[string]Normalizer)
+		}
+		normalizers[k] = param.Normalize
+	}
+	return normalizers
+}
+
+// owners returns the names of the generators whose gen-paths match the provided path. If no generator matches the path,
+// returns the names of the generators whose scans could reach the path.
+func (s *scanner) owners(relPath string) []string {
+	var owners []string
+	for _, k := range s.generators {
+		if s.params[k].GenPaths.Match(relPath) {
+			owners = append(owners, k)
+		}
+	}
+	if len(owners) > 0 {
+		return owners
+	}
+
+	slashPath := filepath.ToSlash(relPath)
+	for _, k := range s.generators {
+		roots := s.params[k].GenPathRoots
+		if roots == nil {
+			owners = append(owners, k)
+			continue
+		}
+		for _, root := range roots {
+			if overlapsRoot(slashPath, root) {
+				owners = append(owners, k)
+				break
+			}
+		}
+	}
+	return owners
+}
+
+// mayContainMatches returns true if the provided path is matched by the gen-paths of any of the generators or if it is
+// within or contains one of their GenPathRoots. If the paths matched by any of the generators cannot be bounded (for
+// example, because its gen-paths specify names), any path may contain matched paths. Errors for other paths are
+// ignored.
+func (s *scanner) mayContainMatches(relPath string) bool {
+	if s.include.Match(relPath) {
+		return true
+	}
+	slashPath := filepath.ToSlash(relPath)
+	for _, k := range s.generators {
+		roots := s.params[k].GenPathRoots
+		if roots == nil {
+			return true
+		}
+		for _, root := range roots {
+			if overlapsRoot(slashPath, root) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// overlapsRoot returns true if the provided slash-separated path is the provided root, is within it or contains it.
+func overlapsRoot(slashPath, root string) bool {
+	return root == "." || slashPath == "." || slashPath == root || strings.HasPrefix(slashPath, root+"/") || strings.HasPrefix(root, slashPath+"/")
+}
+
+func formatGeneratorNames(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = fmt.Sprintf("%q", name)
+	}
+	if len(names) == 1 {
+		return "generator " + quoted[0]
+	}
+	return "generators " + strings.Join(quoted, ", ")
+}
+
+// reusableChecksum returns the checksum computed for the provided path by the previous scan if the metadata of the
+// path is unchanged and the path was not modified shortly before the previous scan started. Returns nil otherwise.
+func (s *scanner) reusableChecksum(relPath string, info os.FileInfo) *fileChecksumInfo {
+	if s.paranoid {
+		return nil
+	}
+	prev, ok := s.prev[relPath]
+	if !ok {
+		return nil
+	}
+	if prev.size != info.Size() || !prev.modTime.Equal(info.ModTime()) || prev.mode != info.Mode() || prev.inode != inode(info) {
+		return nil
+	}
+	if !info.ModTime().Before(s.prevStart.Add(-racyGracePeriod)) {
+		return nil
+	}
+	return prev
+}
+
+// GenPathRoots returns the relative paths of the directories or files beneath which all of the paths matched by the
+// provided configuration reside. Returns nil if the matched paths cannot be bounded, which is the case if the
+// configuration contains any name patterns or any path pattern that starts with a wildcard.
+func GenPathRoots(cfg matcher.NamesPathsCfg) []string {
+	if len(cfg.Names) > 0 {
+		return nil
+	}
+	roots := []string{}
+	for _, p := range cfg.Paths {
+		if path.IsAbs(p) {
+			// absolute patterns never match relative paths
+			continue
+		}
+		var literal []string
+		for _, part := range strings.Split(p, "/") {
+			if strings.ContainsAny(part, `*?[\`) {
+				break
+			}
+			literal = append(literal, part)
+		}
+		if len(literal) == 0 {
+			return nil
+		}
+		root := path.Join(literal...)
+		if root == ".." || strings.HasPrefix(root, "../") {
+			// paths outside of the project are never matched
+			continue
+		}
+		roots = append(roots, root)
+	}
+	return roots
+}
+
+// minimalRoots returns the provided roots sorted and with all of the roots that are contained in other roots removed.
+func minimalRoots(roots []string) []string {
+	sorted := append([]string(nil), roots...)
+	sort.Strings(sorted)
+
+	var minimal []string
+	for _, root := range sorted {
+		contained := false
+		for _, other := range minimal {
+			if other == "." || root == other || strings.HasPrefix(root, other+"/") {
+				contained = true
+				break
+			}
+		}
+		if !contained {
+			minimal = append(minimal, root)
+		}
+	}
+	return minimal
+}
+
+// newChecksum returns the checksum information for the provided path. Symlinks are not followed: a symlink is
+// recorded by its target rather than by the content of the path it links to, so a symlink whose target does not exist
+// is recorded like any other symlink. If the path is a file, the checksum of
+// its content normalized by each of the provided normalizers is also computed.
+func newChecksum(filePath string, info os.FileInfo, normalizers map[string]Normalizer) (*fileChecksumInfo, error) {
+	checksum := &fileChecksumInfo{
+		path:    filePath,
+		kind:    fileKind,
+		size:    info.Size(),
+		modTime: info.ModTime(),
+		mode:    info.Mode(),
+		inode:   inode(info),
+	}
+	switch {
+	case info.IsDir():
+		checksum.kind = dirKind
+		return checksum, nil
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(filePath)
+		if err != nil {
+			return nil, err
+		}
+		checksum.kind = symlinkKind
+		checksum.linkTarget = target
+		return checksum, nil
+	}
+
+	if len(normalizers) > 0 {
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		checksum.sha256checksum = fmt.Sprintf("%x", sha256.Sum256(content))
+		checksum.normalizedChecksums = make(map[string]string)
+		for k, n := range normalizers {
+			checksum.normalizedChecksums[k] = fmt.Sprintf("%x", sha256.Sum256(n.Normalize(content)))
+		}
+		return checksum, nil
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		// file is opened for reading only, so safe to ignore errors on close
+		_ = f.Close()
+	}()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	checksum.sha256checksum = fmt.Sprintf("%x", h.Sum(nil))
+	return checksum, nil
+}
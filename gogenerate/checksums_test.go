@@ -0,0 +1,75 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate_test
+
+import (
+	"testing"
+
+	"github.com/palantir/go-generate/gogenerate"
+	"github.com/palantir/pkg/matcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenPathRoots(t *testing.T) {
+	for currCaseNum, currCase := range []struct {
+		name string
+		cfg  matcher.NamesPathsCfg
+		want []string
+	}{
+		{
+			name: "empty configuration has no roots",
+			cfg:  matcher.NamesPathsCfg{},
+			want: []string{},
+		},
+		{
+			name: "literal paths are roots",
+			cfg: matcher.NamesPathsCfg{
+				Paths: []string{"gen/output.txt", "gen/generated"},
+			},
+			want: []string{"gen/output.txt", "gen/generated"},
+		},
+		{
+			name: "literal prefixes of patterns are roots",
+			cfg: matcher.NamesPathsCfg{
+				Paths: []string{"proto/*/generated", "mocks/mock_*.go", "assets/[ab]"},
+			},
+			want: []string{"proto", "mocks", "assets"},
+		},
+		{
+			name: "paths outside of project are ignored",
+			cfg: matcher.NamesPathsCfg{
+				Paths: []string{"/abs/path", "../sibling/output.txt", "gen/output.txt"},
+			},
+			want: []string{"gen/output.txt"},
+		},
+		{
+			name: "pattern starting with wildcard cannot be bounded",
+			cfg: matcher.NamesPathsCfg{
+				Paths: []string{"gen/output.txt", "*/generated"},
+			},
+			want: nil,
+		},
+		{
+			name: "names cannot be bounded",
+			cfg: matcher.NamesPathsCfg{
+				Names: []string{`.+\.pb\.go`},
+				Paths: []string{"gen/output.txt"},
+			},
+			want: nil,
+		},
+	} {
+		assert.Equal(t, currCase.want, gogenerate.GenPathRoots(currCase.cfg), "Case %d: %s", currCaseNum, currCase.name)
+	}
+}
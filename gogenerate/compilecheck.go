@@ -0,0 +1,167 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CompileCheckTool is the go tool used to check that the packages produced by generators compile.
+type CompileCheckTool string
+
+const (
+	// CompileCheckBuild checks packages using "go build".
+	CompileCheckBuild CompileCheckTool = "build"
+	// CompileCheckVet checks packages using "go vet", which also reports suspicious constructs.
+	CompileCheckVet CompileCheckTool = "vet"
+)
+
+type compileCheck struct {
+	tool CompileCheckTool
+	once bool
+}
+
+// compileErrorRegexp matches a line of output of "go build" or "go vet" that reports an error in a Go file. "go vet"
+// prefixes type checking errors with "vet: ".
+var compileErrorRegexp = regexp.MustCompile(`^(vet: )?(\S+\.go):\d+(:\d+)?: `)
+
+// compileErrors maps the name of a generator to the lines of compiler output attributed to it.
+type compileErrors map[string][]string
+
+// error returns an error that lists the compiler output attributed to every generator. Returns nil if there is no
+// output.
+func (c compileErrors) error() error {
+	if len(c) == 0 {
+		return nil
+	}
+	var sortedKeys []string
+	for k := range c {
+		sortedKeys = append(sortedKeys, k)
+	}
+	sort.Strings(sortedKeys)
+
+	outputParts := []string{fmt.Sprintf("Generators produced Go packages that failed to compile: %v", sortedKeys)}
+	for _, k := range sortedKeys {
+		outputParts = append(outputParts, fmt.Sprintf("  %s:", k))
+		for _, line := range c[k] {
+			outputParts = append(outputParts, fmt.Sprintf("    %s", line))
+		}
+	}
+	return errors.New(strings.Join(outputParts, "\n"))
+}
+
+// packageDirs returns the sorted slash-separated paths of the directories that contain the provided Go files.
+func packageDirs(goFiles []string) []string {
+	dirSet := make(map[string]struct{})
+	for _, goFile := range goFiles {
+		dirSet[path.Dir(filepath.ToSlash(goFile))] = struct{}{}
+	}
+	var dirs []string
+	for dir := range dirSet {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// run runs the compile check tool on the packages in the provided directories and records its output in the provided
+// errors. Every line of output that reports an error in a file is attributed to the generators whose gen-paths match
+// the file or, if no gen-paths match the file, to the generators whose packages contain the file. All other output is
+// attributed to all of the provided generators.
+func (c *compileCheck) run(rootDir string, projectParam ProjectParam, generators []string, pkgDirs map[string][]string, envVars []string, errs compileErrors) error {
+	dirOwners := make(map[string][]string)
+	for _, k := range generators {
+		for _, dir := range pkgDirs[k] {
+			dirOwners[dir] = append(dirOwners[dir], k)
+		}
+	}
+	if len(dirOwners) == 0 {
+		return nil
+	}
+	args := []string{string(c.tool)}
+	if c.tool == CompileCheckBuild {
+		args = append(args, "-o", os.DevNull)
+	}
+	var sortedDirs []string
+	for dir := range dirOwners {
+		sortedDirs = append(sortedDirs, dir)
+	}
+	sort.Strings(sortedDirs)
+	for _, dir := range sortedDirs {
+		args = append(args, "./"+dir)
+	}
+
+	cmd := exec.Command("go", args...)
+	cmd.Dir = rootDir
+	cmd.Env = append(envVars, os.Environ()...)
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*exec.ExitError); !ok {
+		return errors.Wrapf(err, "failed to run go %s", c.tool)
+	}
+
+	for _, line := range strings.Split(string(bytes.TrimSpace(output)), "\n") {
+		if line == "" || strings.HasPrefix(line, "# ") {
+			// package headers are redundant with the paths of the files in the errors
+			continue
+		}
+		owners := generators
+		if match := compileErrorRegexp.FindStringSubmatch(line); match != nil {
+			relPath := match[2]
+			if filepath.IsAbs(relPath) {
+				if rel, err := filepath.Rel(filepath.Join(rootDir, "."), relPath); err == nil {
+					relPath = rel
+				}
+			}
+			relPath = path.Clean(filepath.ToSlash(relPath))
+			line = match[1] + relPath + line[len(match[1])+len(match[2]):]
+			owners = fileOwners(projectParam, generators, relPath, dirOwners)
+		}
+		for _, k := range owners {
+			errs[k] = append(errs[k], line)
+		}
+	}
+	return nil
+}
+
+// fileOwners returns the generators whose gen-paths match the provided file. If no gen-paths match the file, returns
+// the generators that own the directory of the file or, if there are none, all of the provided generators.
+func fileOwners(projectParam ProjectParam, generators []string, relPath string, dirOwners map[string][]string) []string {
+	var owners []string
+	for _, k := range generators {
+		if genPaths := projectParam.Generators[k].GenPaths; genPaths != nil && genPaths.Match(relPath) {
+			owners = append(owners, k)
+		}
+	}
+	if len(owners) > 0 {
+		return owners
+	}
+	if owners, ok := dirOwners[path.Dir(relPath)]; ok {
+		return owners
+	}
+	return generators
+}
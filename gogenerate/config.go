@@ -15,13 +15,48 @@
 package gogenerate
 
 import (
+	"math/rand/v2"
+	"path"
+	"path/filepath"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/palantir/pkg/matcher"
+	"github.com/pkg/errors"
 )
 
 type ProjectParam struct {
 	Generators Generators
+	// Exclude matches the paths that are never considered to be the output of any generator. Excluded directories are
+	// not walked when computing checksums.
+	Exclude matcher.Matcher
+	// ScanErrors specifies how errors encountered while computing checksums are handled.
+	ScanErrors ScanErrorPolicy
+	// CoverageIgnore matches the directories and files whose go:generate directives are not required to be run by any
+	// generator.
+	CoverageIgnore matcher.Matcher
+}
+
+// ScanErrorAction specifies how an error encountered while computing the checksums of generated paths is handled.
+type ScanErrorAction int
+
+const (
+	// ScanErrorFail causes the run to fail with the error.
+	ScanErrorFail ScanErrorAction = iota
+	// ScanErrorWarn causes a warning to be written. The path that caused the error is treated as if it did not exist.
+	ScanErrorWarn
+	// ScanErrorSkip causes the path that caused the error to be silently treated as if it did not exist.
+	ScanErrorSkip
+)
+
+// ScanErrorPolicy specifies the action taken for every class of error that can be encountered while computing checksums.
+// Errors that do not belong to any of these classes always cause the run to fail.
+type ScanErrorPolicy struct {
+	// PermissionDenied is the action taken when a directory or file cannot be read because of its permissions.
+	PermissionDenied ScanErrorAction
+	// NotExist is the action taken when a path is removed while it is being scanned.
+	NotExist ScanErrorAction
 }
 
 type Generators map[string]GeneratorParam
@@ -35,8 +70,184 @@ func (g Generators) SortedKeys() []string {
 	return sorted
 }
 
+// ExecutionOrder returns the names of the generators in the order in which they should be run. Every generator is
+// ordered after all of the generators it depends on, and generators that are not ordered by dependencies are returned
+// in lexicographical order. Returns an error if a generator depends on a generator that does not exist or if the
+// dependencies contain a cycle.
+func (g Generators) ExecutionOrder() ([]string, error) {
+	return g.executionOrder(func(ready []string) int {
+		return 0
+	})
+}
+
+// ShuffledExecutionOrder returns the names of the generators in an order that is randomized using the provided source
+// but still orders every generator after all of the generators it depends on.
+func (g Generators) ShuffledExecutionOrder(r *rand.Rand) ([]string, error) {
+	return g.executionOrder(func(ready []string) int {
+		return r.IntN(len(ready))
+	})
+}
+
+// Affected returns the sorted names of the generators that are affected by changes to the provided slash-separated
+// paths relative to the project directory, along with all of the generators that depend on them directly or
+// transitively. A generator is affected by a change to a path if the path is in its GoGenDir (but not in a
+// subdirectory of it) or is matched by its Inputs or GenPaths.
+func (g Generators) Affected(changedPaths []string) []string {
+	affected := make(map[string]struct{})
+	for k, v := range g {
+		for _, changedPath := range changedPaths {
+			if v.reads(changedPath) || (v.GenPaths != nil && v.GenPaths.Match(changedPath)) {
+				affected[k] = struct{}{}
+				break
+			}
+		}
+	}
+
+	// add dependents until a fixed point is reached
+	for added := true; added; {
+		added = false
+		for k, v := range g {
+			if _, ok := affected[k]; ok {
+				continue
+			}
+			for _, dep := range v.DependsOn {
+				if _, ok := affected[dep]; ok {
+					affected[k] = struct{}{}
+					added = true
+					break
+				}
+			}
+		}
+	}
+
+	var sorted []string
+	for k := range affected {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// Subset returns the generators with the provided names. Dependencies on generators that are not in the subset are
+// removed so that the subset can be ordered.
+func (g Generators) Subset(names []string) Generators {
+	subset := make(Generators)
+	for _, k := range names {
+		if v, ok := g[k]; ok {
+			subset[k] = v
+		}
+	}
+	for k, v := range subset {
+		var deps []string
+		for _, dep := range v.DependsOn {
+			if _, ok := subset[dep]; ok {
+				deps = append(deps, dep)
+			}
+		}
+		v.DependsOn = deps
+		subset[k] = v
+	}
+	return subset
+}
+
+// executionOrder returns a topological ordering of the generators. The choose function is called with the sorted names
+// of the generators whose dependencies have all been ordered and returns the index of the one that should be next.
+func (g Generators) executionOrder(choose func(ready []string) int) ([]string, error) {
+	remainingDeps := make(map[string]int)
+	dependents := make(map[string][]string)
+	for _, k := range g.SortedKeys() {
+		for _, dep := range g[k].DependsOn {
+			if _, ok := g[dep]; !ok {
+				return nil, errors.Errorf("generator %q depends on generator %q, which does not exist", k, dep)
+			}
+			remainingDeps[k]++
+			dependents[dep] = append(dependents[dep], k)
+		}
+	}
+
+	var ready []string
+	for _, k := range g.SortedKeys() {
+		if remainingDeps[k] == 0 {
+			ready = append(ready, k)
+		}
+	}
+	var order []string
+	for len(ready) > 0 {
+		i := choose(ready)
+		curr := ready[i]
+		ready = append(ready[:i], ready[i+1:]...)
+		order = append(order, curr)
+
+		for _, dependent := range dependents[curr] {
+			remainingDeps[dependent]--
+			if remainingDeps[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+		sort.Strings(ready)
+	}
+
+	if len(order) != len(g) {
+		var cycle []string
+		for _, k := range g.SortedKeys() {
+			if remainingDeps[k] > 0 {
+				cycle = append(cycle, k)
+			}
+		}
+		return nil, errors.Errorf("generators [%s] cannot be ordered because their dependencies contain a cycle", strings.Join(cycle, " "))
+	}
+	return order, nil
+}
+
 type GeneratorParam struct {
-	GoGenDir    string
+	GoGenDir string
+	// Command is the command that is run in GoGenDir instead of "go generate". The first element is the executable. If
+	// empty, "go generate" is run.
+	Command []string
+	// Flags contains additional arguments that are provided to "go generate" (or to Command if it is not empty).
+	Flags       []string
 	GenPaths    matcher.Matcher
 	Environment map[string]string
+	// Inputs matches the paths outside of GoGenDir that the generator reads. Changes to these paths cause the generator
+	// to be run by Watch.
+	Inputs matcher.Matcher
+	// DependsOn contains the names of the generators that must be run before this generator.
+	DependsOn []string
+	// Timeout is the maximum duration of a run of the generator. If 0, runs of the generator do not time out.
+	Timeout time.Duration
+	// Tags contains labels that describe the generator.
+	Tags []string
+	// GenPathRoots contains the relative paths of the directories or files beneath which all of the paths matched by
+	// GenPaths reside and is used to limit the directories that are walked when computing checksums. If nil, the
+	// entire project is walked.
+	GenPathRoots []string
+	// IgnoreMode specifies whether changes to the permission bits of the paths matched by GenPaths are ignored.
+	IgnoreMode bool
+	// Normalize specifies the transformations applied to the content of the files matched by GenPaths before it is
+	// compared.
+	Normalize Normalizer
+	// RequireGeneratedHeader specifies whether every Go file matched by GenPaths must have the standard generated code
+	// header after the generator is run.
+	RequireGeneratedHeader bool
+	// Gofmt specifies whether the Go files matched by GenPaths are checked or fixed with gofmt after the generator is
+	// run.
+	Gofmt GofmtAction
 }
+
+// reads returns true if the provided slash-separated path relative to the project directory is an input of the
+// generator: that is, if the path is in its GoGenDir (but not in a subdirectory of it) or is matched by its Inputs.
+func (p GeneratorParam) reads(relPath string) bool {
+	return path.Dir(relPath) == path.Clean(filepath.ToSlash(p.GoGenDir)) || (p.Inputs != nil && p.Inputs.Match(relPath))
+}
+
+// GofmtAction specifies how the Go files produced by a generator are validated with gofmt.
+type GofmtAction int
+
+const (
+	// GofmtNone causes the files to not be validated.
+	GofmtNone GofmtAction = iota
+	// GofmtCheck causes the run to fail if any file is not formatted or is not valid Go.
+	GofmtCheck
+	// GofmtFix causes files that are not formatted to be formatted. The run fails if any file is not valid Go.
+	GofmtFix
+)
@@ -15,29 +15,220 @@
 package config
 
 import (
+	"regexp"
+
 	"github.com/palantir/go-generate/gogenerate"
-	v0 "github.com/palantir/go-generate/gogenerate/config/internal/v0"
+	v1 "github.com/palantir/go-generate/gogenerate/config/internal/v1"
+	"github.com/palantir/pkg/matcher"
+	"github.com/pkg/errors"
 )
 
-type ProjectConfig v0.ProjectConfig
+type ProjectConfig v1.ProjectConfig
 
+// ToParam returns the parameters specified by the configuration. The configuration must be valid: ToParam panics if
+// Validate returns an error. Configurations returned by LoadConfig are always valid, but configurations that are
+// unmarshalled or constructed directly should be validated first.
 func (cfg *ProjectConfig) ToParam() gogenerate.ProjectParam {
+	projectParam, err := cfg.toParam()
+	if err != nil {
+		panic(err)
+	}
+	return projectParam
+}
+
+// Validate returns an error if a value of the configuration, such as a regular expression or a timeout, is invalid.
+func (cfg *ProjectConfig) Validate() error {
+	_, err := cfg.toParam()
+	return err
+}
+
+func (cfg *ProjectConfig) toParam() (gogenerate.ProjectParam, error) {
 	generators := make(gogenerate.Generators)
 	for k, v := range cfg.Generators {
-		v := GeneratorConfig(v)
-		generators[k] = v.ToParam()
+		v := GeneratorConfig(v).withDefaults(cfg.Defaults)
+		param, err := v.toParam()
+		if err != nil {
+			return gogenerate.ProjectParam{}, errors.Wrapf(err, "invalid configuration of generator %q", k)
+		}
+		generators[k] = param
+	}
+	exclude, err := namesPathsMatcher("exclude", cfg.Exclude)
+	if err != nil {
+		return gogenerate.ProjectParam{}, err
+	}
+	coverageIgnore, err := namesPathsMatcher("coverage-ignore", cfg.CoverageIgnore)
+	if err != nil {
+		return gogenerate.ProjectParam{}, err
 	}
 	return gogenerate.ProjectParam{
 		Generators: generators,
+		Exclude:    exclude,
+		ScanErrors: gogenerate.ScanErrorPolicy{
+			PermissionDenied: scanErrorAction(cfg.ScanErrors.PermissionDenied),
+			NotExist:         scanErrorAction(cfg.ScanErrors.NotExist),
+		},
+		CoverageIgnore: coverageIgnore,
+	}, nil
+}
+
+// namesPathsMatcher returns the matcher specified by the provided configuration. Returns an error if any of its names
+// is not a valid regular expression.
+func namesPathsMatcher(key string, cfg matcher.NamesPathsCfg) (matcher.Matcher, error) {
+	for _, name := range cfg.Names {
+		if _, err := regexp.Compile(name); err != nil {
+			return nil, errors.Wrapf(err, "invalid %s regular expression %q", key, name)
+		}
 	}
+	return cfg.Matcher(), nil
 }
 
-type GeneratorConfig v0.GeneratorConfig
+func scanErrorAction(action string) gogenerate.ScanErrorAction {
+	switch action {
+	case "warn":
+		return gogenerate.ScanErrorWarn
+	case "skip":
+		return gogenerate.ScanErrorSkip
+	default:
+		return gogenerate.ScanErrorFail
+	}
+}
 
+type GeneratorConfig v1.GeneratorConfig
+
+// ToParam returns the parameters specified by the configuration. The configuration must be valid: ToParam panics if
+// Validate returns an error.
 func (cfg *GeneratorConfig) ToParam() gogenerate.GeneratorParam {
+	param, err := cfg.toParam()
+	if err != nil {
+		panic(err)
+	}
+	return param
+}
+
+// Validate returns an error if a value of the configuration, such as a regular expression or a timeout, is invalid.
+func (cfg *GeneratorConfig) Validate() error {
+	_, err := cfg.toParam()
+	return err
+}
+
+func (cfg *GeneratorConfig) toParam() (gogenerate.GeneratorParam, error) {
+	timeout, err := v1.ParseTimeout(cfg.Timeout)
+	if err != nil {
+		return gogenerate.GeneratorParam{}, err
+	}
+	genPaths, err := namesPathsMatcher("gen-paths", cfg.GenPaths)
+	if err != nil {
+		return gogenerate.GeneratorParam{}, err
+	}
+	exclude, err := namesPathsMatcher("exclude", cfg.Exclude)
+	if err != nil {
+		return gogenerate.GeneratorParam{}, err
+	}
+	genPaths = withExclude(genPaths, cfg.Exclude, exclude)
+	inputs, err := namesPathsMatcher("inputs", cfg.Inputs)
+	if err != nil {
+		return gogenerate.GeneratorParam{}, err
+	}
+	normalize, err := normalizer(cfg.Normalize)
+	if err != nil {
+		return gogenerate.GeneratorParam{}, err
+	}
 	return gogenerate.GeneratorParam{
-		GoGenDir:    cfg.GoGenDir,
-		GenPaths:    cfg.GenPaths.Matcher(),
-		Environment: cfg.Environment,
+		GoGenDir:               cfg.GoGenDir,
+		Command:                cfg.Command,
+		Flags:                  cfg.Flags,
+		GenPaths:               genPaths,
+		Environment:            cfg.Environment,
+		Inputs:                 inputs,
+		DependsOn:              cfg.DependsOn,
+		Timeout:                timeout,
+		Tags:                   cfg.Tags,
+		GenPathRoots:           gogenerate.GenPathRoots(cfg.GenPaths),
+		IgnoreMode:             boolValue(cfg.IgnoreMode),
+		Normalize:              normalize,
+		RequireGeneratedHeader: boolValue(cfg.OutputChecks.RequireGeneratedHeader),
+		Gofmt:                  gofmtAction(cfg.OutputChecks.Gofmt),
+	}, nil
+}
+
+// withDefaults returns the configuration with the provided defaults applied as described by v1.DefaultsConfig.
+func (cfg GeneratorConfig) withDefaults(defaults v1.DefaultsConfig) GeneratorConfig {
+	if len(defaults.Environment) > 0 {
+		environment := make(map[string]string, len(defaults.Environment)+len(cfg.Environment))
+		for k, v := range defaults.Environment {
+			environment[k] = v
+		}
+		for k, v := range cfg.Environment {
+			environment[k] = v
+		}
+		cfg.Environment = environment
+	}
+	if cfg.Flags == nil {
+		cfg.Flags = defaults.Flags
+	}
+	if !defaults.Exclude.Empty() {
+		var exclude matcher.NamesPathsCfg
+		exclude.Add(defaults.Exclude)
+		exclude.Add(cfg.Exclude)
+		cfg.Exclude = exclude
+	}
+	if cfg.Timeout == "" {
+		cfg.Timeout = defaults.Timeout
+	}
+	if cfg.IgnoreMode == nil {
+		cfg.IgnoreMode = defaults.IgnoreMode
+	}
+	if cfg.Normalize.IgnoreLines == nil {
+		cfg.Normalize.IgnoreLines = defaults.Normalize.IgnoreLines
+	}
+	if cfg.Normalize.FoldCRLF == nil {
+		cfg.Normalize.FoldCRLF = defaults.Normalize.FoldCRLF
+	}
+	if cfg.Normalize.TrimTrailingWhitespace == nil {
+		cfg.Normalize.TrimTrailingWhitespace = defaults.Normalize.TrimTrailingWhitespace
+	}
+	if cfg.OutputChecks.RequireGeneratedHeader == nil {
+		cfg.OutputChecks.RequireGeneratedHeader = defaults.OutputChecks.RequireGeneratedHeader
+	}
+	if cfg.OutputChecks.Gofmt == "" {
+		cfg.OutputChecks.Gofmt = defaults.OutputChecks.Gofmt
+	}
+	return cfg
+}
+
+// withExclude returns a matcher that matches the paths matched by the provided gen-paths matcher that are not matched
+// by the provided exclude matcher, which is specified by the provided configuration.
+func withExclude(genPaths matcher.Matcher, excludeCfg matcher.NamesPathsCfg, exclude matcher.Matcher) matcher.Matcher {
+	if excludeCfg.Empty() {
+		return genPaths
+	}
+	return matcher.All(genPaths, matcher.Not(exclude))
+}
+
+// boolValue returns the value of the provided setting, which is false if it is not specified.
+func boolValue(b *bool) bool {
+	return b != nil && *b
+}
+
+func gofmtAction(action string) gogenerate.GofmtAction {
+	switch action {
+	case "check":
+		return gogenerate.GofmtCheck
+	case "fix":
+		return gogenerate.GofmtFix
+	default:
+		return gogenerate.GofmtNone
+	}
+}
+
+func normalizer(cfg v1.NormalizeConfig) (gogenerate.Normalizer, error) {
+	ignoreLines, err := cfg.IgnoreLinesRegexps()
+	if err != nil {
+		return gogenerate.Normalizer{}, err
 	}
+	return gogenerate.Normalizer{
+		IgnoreLines:            ignoreLines,
+		FoldCRLF:               boolValue(cfg.FoldCRLF),
+		TrimTrailingWhitespace: boolValue(cfg.TrimTrailingWhitespace),
+	}, nil
 }
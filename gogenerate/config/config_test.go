@@ -0,0 +1,279 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/palantir/go-generate/gogenerate"
+	"github.com/palantir/go-generate/gogenerate/config"
+	v1 "github.com/palantir/go-generate/gogenerate/config/internal/v1"
+	"github.com/palantir/pkg/matcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestScanErrorsToParam(t *testing.T) {
+	var cfg config.ProjectConfig
+	err := yaml.Unmarshal([]byte(`
+scan-errors:
+  permission-denied: warn
+  not-exist: skip
+`), &cfg)
+	require.NoError(t, err)
+
+	assert.Equal(t, gogenerate.ScanErrorPolicy{
+		PermissionDenied: gogenerate.ScanErrorWarn,
+		NotExist:         gogenerate.ScanErrorSkip,
+	}, cfg.ToParam().ScanErrors)
+}
+
+func TestScanErrorsInvalidAction(t *testing.T) {
+	_, err := config.UpgradeConfig([]byte(`
+version: 1
+scan-errors:
+  permission-denied: ignore
+`))
+	assert.EqualError(t, err, `failed to unmarshal generate-plugin v1 configuration: invalid scan error action "ignore": must be one of "fail", "warn" or "skip"`)
+}
+
+func TestNormalizeInvalidRegexp(t *testing.T) {
+	_, err := config.UpgradeConfig([]byte(`
+version: 1
+generators:
+  foo:
+    normalize:
+      ignore-lines:
+        - "[a-"
+`))
+	assert.EqualError(t, err, "failed to unmarshal generate-plugin v1 configuration: invalid ignore-lines regular expression \"[a-\": error parsing regexp: missing closing ]: `[a-`")
+}
+
+func TestGofmtInvalidAction(t *testing.T) {
+	_, err := config.UpgradeConfig([]byte(`
+version: 1
+generators:
+  foo:
+    output-checks:
+      gofmt: format
+`))
+	assert.EqualError(t, err, `failed to unmarshal generate-plugin v1 configuration: invalid gofmt action "format": must be one of "check" or "fix"`)
+}
+
+func TestGeneratorToParam(t *testing.T) {
+	var cfg config.ProjectConfig
+	err := yaml.Unmarshal([]byte(`
+version: 1
+generators:
+  foo:
+    go-generate-dir: gen
+    command: [buf, generate]
+    flags: ["--template", "buf.gen.yaml"]
+    timeout: 90s
+    tags: [proto, slow]
+    output-checks:
+      require-generated-header: true
+      gofmt: check
+`), &cfg)
+	require.NoError(t, err)
+
+	param := cfg.ToParam().Generators["foo"]
+	assert.Equal(t, []string{"buf", "generate"}, param.Command)
+	assert.Equal(t, []string{"--template", "buf.gen.yaml"}, param.Flags)
+	assert.Equal(t, 90*time.Second, param.Timeout)
+	assert.Equal(t, []string{"proto", "slow"}, param.Tags)
+	assert.True(t, param.RequireGeneratedHeader)
+	assert.Equal(t, gogenerate.GofmtCheck, param.Gofmt)
+}
+
+func TestV1InvalidGenerator(t *testing.T) {
+	for currCaseNum, currCase := range []struct {
+		name      string
+		generator string
+		wantError string
+	}{
+		{
+			name:      "invalid timeout",
+			generator: `timeout: soon`,
+			wantError: `failed to unmarshal generate-plugin v1 configuration: invalid timeout "soon": time: invalid duration "soon"`,
+		},
+		{
+			name:      "negative timeout",
+			generator: `timeout: -1m`,
+			wantError: `failed to unmarshal generate-plugin v1 configuration: invalid timeout "-1m": must be positive`,
+		},
+		{
+			name:      "empty command",
+			generator: `command: []`,
+			wantError: `failed to unmarshal generate-plugin v1 configuration: command must not be empty`,
+		},
+		{
+			name: "invalid gofmt action",
+			generator: `output-checks:
+      gofmt: format`,
+			wantError: `failed to unmarshal generate-plugin v1 configuration: invalid gofmt action "format": must be one of "check" or "fix"`,
+		},
+		{
+			name:      "v0 field",
+			generator: `gofmt: fix`,
+			wantError: "failed to unmarshal generate-plugin v1 configuration: yaml: unmarshal errors:\n  line 4: field gofmt not found in type v1.generatorConfigAlias",
+		},
+	} {
+		_, err := config.UpgradeConfig([]byte(`version: 1
+generators:
+  foo:
+    ` + currCase.generator + `
+`))
+		assert.EqualError(t, err, currCase.wantError, "Case %d: %s", currCaseNum, currCase.name)
+	}
+}
+
+func TestValidateInvalid(t *testing.T) {
+	for currCaseNum, currCase := range []struct {
+		name      string
+		generator v1.GeneratorConfig
+		wantError string
+	}{
+		{
+			name:      "invalid timeout",
+			generator: v1.GeneratorConfig{Timeout: "soon"},
+			wantError: `invalid configuration of generator "foo": invalid timeout "soon": time: invalid duration "soon"`,
+		},
+		{
+			name:      "negative timeout",
+			generator: v1.GeneratorConfig{Timeout: "-1m"},
+			wantError: `invalid configuration of generator "foo": invalid timeout "-1m": must be positive`,
+		},
+		{
+			name:      "invalid ignore-lines regular expression",
+			generator: v1.GeneratorConfig{Normalize: v1.NormalizeConfig{IgnoreLines: []string{"[a-"}}},
+			wantError: "invalid configuration of generator \"foo\": invalid ignore-lines regular expression \"[a-\": error parsing regexp: missing closing ]: `[a-`",
+		},
+		{
+			name:      "invalid gen-paths regular expression",
+			generator: v1.GeneratorConfig{GenPaths: matcher.NamesPathsCfg{Names: []string{"("}}},
+			wantError: "invalid configuration of generator \"foo\": invalid gen-paths regular expression \"(\": error parsing regexp: missing closing ): `(`",
+		},
+	} {
+		cfg := config.ProjectConfig{
+			Generators: map[string]v1.GeneratorConfig{
+				"foo": currCase.generator,
+			},
+		}
+		assert.EqualError(t, cfg.Validate(), currCase.wantError, "Case %d: %s", currCaseNum, currCase.name)
+		assert.Panics(t, func() { cfg.ToParam() }, "Case %d: %s", currCaseNum, currCase.name)
+	}
+}
+
+func TestDefaultsToParam(t *testing.T) {
+	var cfg config.ProjectConfig
+	err := yaml.Unmarshal([]byte(`
+version: 1
+defaults:
+  environment:
+    GOFLAGS: -mod=mod
+    GO111MODULE: "on"
+  flags: ["-x"]
+  exclude:
+    names: [doc.go]
+  timeout: 1m
+  ignore-mode: true
+  normalize:
+    ignore-lines:
+      - "^// Generated at .*$"
+    fold-crlf: true
+  output-checks:
+    require-generated-header: true
+    gofmt: check
+generators:
+  inherits:
+    go-generate-dir: inherits
+    gen-paths:
+      paths: [inherits/generated]
+  overrides:
+    go-generate-dir: overrides
+    gen-paths:
+      paths: [overrides/generated]
+    exclude:
+      paths: [overrides/generated/static]
+    environment:
+      GOFLAGS: -mod=vendor
+      GOOS: linux
+    flags: []
+    timeout: 5m
+    normalize:
+      ignore-lines:
+        - "^// Version .*$"
+      trim-trailing-whitespace: true
+    output-checks:
+      gofmt: fix
+  disables:
+    go-generate-dir: disables
+    ignore-mode: false
+    normalize:
+      ignore-lines: []
+      fold-crlf: false
+    output-checks:
+      require-generated-header: false
+`), &cfg)
+	require.NoError(t, err)
+	generators := cfg.ToParam().Generators
+
+	inherits := generators["inherits"]
+	assert.Equal(t, map[string]string{"GOFLAGS": "-mod=mod", "GO111MODULE": "on"}, inherits.Environment)
+	assert.Equal(t, []string{"-x"}, inherits.Flags)
+	assert.Equal(t, time.Minute, inherits.Timeout)
+	assert.True(t, inherits.IgnoreMode)
+	assert.Equal(t, 1, len(inherits.Normalize.IgnoreLines))
+	assert.True(t, inherits.Normalize.FoldCRLF)
+	assert.False(t, inherits.Normalize.TrimTrailingWhitespace)
+	assert.True(t, inherits.RequireGeneratedHeader)
+	assert.Equal(t, gogenerate.GofmtCheck, inherits.Gofmt)
+	assert.True(t, inherits.GenPaths.Match("inherits/generated/foo.go"))
+	assert.False(t, inherits.GenPaths.Match("inherits/generated/doc.go"))
+
+	overrides := generators["overrides"]
+	assert.Equal(t, map[string]string{"GOFLAGS": "-mod=vendor", "GO111MODULE": "on", "GOOS": "linux"}, overrides.Environment)
+	assert.Equal(t, []string{}, overrides.Flags)
+	assert.Equal(t, 5*time.Minute, overrides.Timeout)
+	assert.True(t, overrides.IgnoreMode)
+	require.Equal(t, 1, len(overrides.Normalize.IgnoreLines))
+	assert.Equal(t, "^// Version .*$", overrides.Normalize.IgnoreLines[0].String())
+	assert.True(t, overrides.Normalize.FoldCRLF)
+	assert.True(t, overrides.Normalize.TrimTrailingWhitespace)
+	assert.True(t, overrides.RequireGeneratedHeader)
+	assert.Equal(t, gogenerate.GofmtFix, overrides.Gofmt)
+	assert.True(t, overrides.GenPaths.Match("overrides/generated/foo.go"))
+	assert.False(t, overrides.GenPaths.Match("overrides/generated/doc.go"))
+	assert.False(t, overrides.GenPaths.Match("overrides/generated/static/foo.go"))
+
+	disables := generators["disables"]
+	assert.False(t, disables.IgnoreMode)
+	assert.Equal(t, 0, len(disables.Normalize.IgnoreLines))
+	assert.False(t, disables.Normalize.FoldCRLF)
+	assert.False(t, disables.Normalize.TrimTrailingWhitespace)
+	assert.False(t, disables.RequireGeneratedHeader)
+	assert.Equal(t, gogenerate.GofmtCheck, disables.Gofmt)
+}
+
+func TestDefaultsInvalidTimeout(t *testing.T) {
+	_, err := config.UpgradeConfig([]byte(`version: 1
+defaults:
+  timeout: soon
+`))
+	assert.EqualError(t, err, `failed to unmarshal generate-plugin v1 configuration: invalid timeout "soon": time: invalid duration "soon"`)
+}
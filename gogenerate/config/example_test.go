@@ -23,6 +23,7 @@ import (
 
 func Example() {
 	yml := `
+version: 1
 generators:
   foo:
     go-generate-dir: testbar
@@ -39,5 +40,5 @@ generators:
 		panic(err)
 	}
 	fmt.Printf("%q", fmt.Sprintf("%+v", cfg))
-	// Output: "{Generators:map[foo:{GoGenDir:testbar GenPaths:{Names:[bar] Paths:[testbar/output.txt]} Environment:map[GOOS:darwin]}]}"
+	// Output: "{ConfigWithVersion:{Version:1} Generators:map[foo:{GoGenDir:testbar Command:[] Flags:[] GenPaths:{Names:[bar] Paths:[testbar/output.txt]} Exclude:{Names:[] Paths:[]} Environment:map[GOOS:darwin] Inputs:{Names:[] Paths:[]} DependsOn:[] Timeout: Tags:[] IgnoreMode:<nil> Normalize:{IgnoreLines:[] FoldCRLF:<nil> TrimTrailingWhitespace:<nil>} OutputChecks:{RequireGeneratedHeader:<nil> Gofmt:}}] Exclude:{Names:[] Paths:[]} ScanErrors:{PermissionDenied: NotExist:} CoverageIgnore:{Names:[] Paths:[]} DiscoverFragments:false Defaults:{Environment:map[] Flags:[] Exclude:{Names:[] Paths:[]} Timeout: IgnoreMode:<nil> Normalize:{IgnoreLines:[] FoldCRLF:<nil> TrimTrailingWhitespace:<nil>} OutputChecks:{RequireGeneratedHeader:<nil> Gofmt:}}}"
 }
@@ -0,0 +1,349 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/palantir/go-generate/gogenerate"
+	v1 "github.com/palantir/go-generate/gogenerate/config/internal/v1"
+	"github.com/palantir/pkg/matcher"
+	"github.com/pkg/errors"
+)
+
+// FragmentFileName is the name of the configuration fragment files that are discovered in the subdirectories of the
+// project if DiscoverFragments is true.
+const FragmentFileName = "generate.yml"
+
+// ToParamWithFragments returns the parameters for the configuration, which was read from the provided file. If
+// DiscoverFragments is true, the generators of the configuration fragments in the subdirectories of the provided
+// project directory (other than the provided file) are merged into the returned parameters (see Fragments). A generator
+// conflicts with another generator if they have the same name or if their gen-paths overlap (see
+// genPathsOwner.conflict). Returns an error if a fragment cannot be read or a generator in a fragment conflicts with another
+// generator.
+func (cfg *ProjectConfig) ToParamWithFragments(rootDir, cfgFile string) (gogenerate.ProjectParam, error) {
+	projectParam, err := cfg.toParam()
+	if err != nil {
+		return gogenerate.ProjectParam{}, err
+	}
+	if !cfg.DiscoverFragments {
+		return projectParam, nil
+	}
+	fragments, err := Fragments(rootDir, cfgFile, projectParam.Exclude)
+	if err != nil {
+		return gogenerate.ProjectParam{}, err
+	}
+	if projectParam.Generators == nil {
+		projectParam.Generators = make(gogenerate.Generators)
+	}
+
+	// source of every generator for conflict errors
+	sources := make(map[string]string)
+	for k := range projectParam.Generators {
+		sources[k] = cfgFile
+	}
+	var owners []genPathsOwner
+	for _, k := range sortedGeneratorNames(cfg.Generators) {
+		owners = append(owners, newGenPathsOwner(k, ".", cfg.Generators[k].GenPaths, projectParam.Generators[k].GenPaths))
+	}
+
+	for _, fragment := range fragments {
+		for _, k := range sortedGeneratorNames(fragment.Generators) {
+			name, genParam, err := fragment.generatorParam(k, cfg.Defaults)
+			if err != nil {
+				return gogenerate.ProjectParam{}, err
+			}
+			if source, ok := sources[name]; ok {
+				return gogenerate.ProjectParam{}, errors.Errorf("generator %q in %s conflicts with the generator of the same name in %s", name, fragment.File, source)
+			}
+			sources[name] = fragment.File
+			genPaths := matcher.NamesPathsCfg{
+				Names: fragment.Generators[k].GenPaths.Names,
+			}
+			for _, p := range fragment.Generators[k].GenPaths.Paths {
+				genPaths.Paths = append(genPaths.Paths, path.Join(fragment.Dir, p))
+			}
+			owner := newGenPathsOwner(name, fragment.Dir, genPaths, genParam.GenPaths)
+			for _, other := range owners {
+				if conflict := owner.conflict(other); conflict != "" {
+					return gogenerate.ProjectParam{}, errors.Errorf("gen-paths of generator %q in %s overlap with the gen-paths of generator %q in %s: %s", name, fragment.File, other.name, sources[other.name], conflict)
+				}
+			}
+			owners = append(owners, owner)
+			projectParam.Generators[name] = genParam
+		}
+	}
+	return projectParam, nil
+}
+
+// genPathsOwner is a generator whose gen-paths may conflict with the gen-paths of other generators.
+type genPathsOwner struct {
+	name string
+	// paths are the slash-separated gen-paths paths of the generator relative to the project directory
+	paths []string
+	// names are the gen-paths names of the generator
+	names []string
+	// namesDir is the slash-separated path of the directory relative to the project directory in which names match
+	namesDir string
+	matcher  matcher.Matcher
+}
+
+func newGenPathsOwner(name, namesDir string, genPaths matcher.NamesPathsCfg, genPathsMatcher matcher.Matcher) genPathsOwner {
+	var paths []string
+	for _, p := range genPaths.Paths {
+		paths = append(paths, path.Clean(p))
+	}
+	return genPathsOwner{
+		name:     name,
+		paths:    paths,
+		names:    genPaths.Names,
+		namesDir: namesDir,
+		matcher:  genPathsMatcher,
+	}
+}
+
+// conflict returns a description of the overlap between the gen-paths of the generator and the gen-paths of the
+// provided generator, or an empty string if they do not overlap. The gen-paths overlap if a gen-paths path of one is
+// matched by the gen-paths of the other: for example, a gen-paths path overlaps with a gen-paths path of any of the
+// directories that contain it. Because it cannot be determined whether different expressions match the same paths,
+// gen-paths names only overlap if they are the same expression and the directories in which they match overlap.
+func (o genPathsOwner) conflict(other genPathsOwner) string {
+	for _, p := range o.paths {
+		if other.matcher != nil && other.matcher.Match(p) {
+			return fmt.Sprintf("path %q is matched by both", p)
+		}
+	}
+	for _, p := range other.paths {
+		if o.matcher != nil && o.matcher.Match(p) {
+			return fmt.Sprintf("path %q is matched by both", p)
+		}
+	}
+	if !dirsOverlap(o.namesDir, other.namesDir) {
+		return ""
+	}
+	for _, name := range o.names {
+		for _, otherName := range other.names {
+			if name == otherName {
+				return fmt.Sprintf("name %q is specified by both", name)
+			}
+		}
+	}
+	return ""
+}
+
+// dirsOverlap returns true if one of the provided slash-separated directories contains the other.
+func dirsOverlap(a, b string) bool {
+	return a == "." || b == "." || a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
+
+// Fragment is a configuration fragment: a "generate.yml" file in a subdirectory of the project that specifies
+// generators.
+type Fragment struct {
+	// File is the slash-separated path of the fragment relative to the project directory.
+	File string
+	// Dir is the slash-separated path of the directory that contains the fragment relative to the project directory.
+	Dir string
+	// Generators is a map from the name of a generator (without the namespace of the fragment) to its configuration.
+	Generators map[string]GeneratorConfig
+}
+
+// Fragments returns the configuration fragments in the subdirectories of the provided project directory sorted by
+// path. The provided project configuration file is not a fragment even if it is named "generate.yml". Directories that
+// are ignored by the go tool ("vendor", "testdata" and directories whose names begin with "." or "_") and paths matched
+// by the provided exclude matcher (which may be nil) are skipped. A fragment may be of any supported configuration
+// version, but may only specify generators. Returns an error if a fragment cannot be read or specifies anything other
+// than generators.
+func Fragments(rootDir, cfgFile string, exclude matcher.Matcher) ([]Fragment, error) {
+	root := filepath.Join(rootDir, ".")
+	var fragments []Fragment
+	if err := filepath.WalkDir(root, func(currPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(root, currPath)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if d.IsDir() {
+			if relPath == "." {
+				return nil
+			}
+			if name := d.Name(); name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || (exclude != nil && exclude.Match(relPath)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != FragmentFileName || path.Dir(relPath) == "." || (exclude != nil && exclude.Match(relPath)) || isSameFile(currPath, cfgFile) {
+			return nil
+		}
+		fragment, err := readFragment(currPath, relPath)
+		if err != nil {
+			return err
+		}
+		fragments = append(fragments, fragment)
+		return nil
+	}); err != nil {
+		return nil, errors.Wrapf(err, "failed to discover configuration fragments")
+	}
+	sort.Slice(fragments, func(i, j int) bool {
+		return fragments[i].File < fragments[j].File
+	})
+	return fragments, nil
+}
+
+func readFragment(filePath, relPath string) (Fragment, error) {
+	cfgBytes, err := os.ReadFile(filePath)
+	if err != nil {
+		return Fragment{}, errors.Wrapf(err, "failed to read configuration fragment %s", relPath)
+	}
+	cfg, err := LoadConfig(relPath, cfgBytes)
+	if err != nil {
+		return Fragment{}, err
+	}
+	if !cfg.Exclude.Empty() || !cfg.CoverageIgnore.Empty() || cfg.ScanErrors != (v1.ScanErrorsConfig{}) || cfg.DiscoverFragments || !reflect.DeepEqual(cfg.Defaults, v1.DefaultsConfig{}) {
+		return Fragment{}, errors.Errorf("invalid configuration fragment %s: fragments may only specify generators", relPath)
+	}
+	fragment := Fragment{
+		File:       relPath,
+		Dir:        path.Dir(relPath),
+		Generators: make(map[string]GeneratorConfig),
+	}
+	for k, v := range cfg.Generators {
+		if strings.Contains(k, ":") {
+			return Fragment{}, errors.Errorf("invalid configuration fragment %s: generator name %q must not contain ':'", relPath, k)
+		}
+		fragment.Generators[k] = GeneratorConfig(v)
+	}
+	return fragment, nil
+}
+
+// FragmentGeneratorName returns the name of the generator with the provided name in the fragment in the provided
+// directory, which is the directory followed by ':' and the name. For example, the generator "mocks" in the fragment
+// "teams/foo/generate.yml" is named "teams/foo:mocks".
+func FragmentGeneratorName(fragmentDir, name string) string {
+	return fragmentDir + ":" + name
+}
+
+// generatorParam returns the namespaced name and the parameters of the generator with the provided name. The
+// go-generate-dir and the gen-paths, exclude and inputs paths of the generator are resolved relative to the directory of the
+// fragment and must be within it, and its gen-paths and inputs names only match paths within the directory. A
+// dependency that does not contain ':' is the name of a generator in the same fragment, while other dependencies are
+// the full names of generators: generators in the project configuration are referred to by their names prefixed with
+// ':' (for example, ":proto"). The provided defaults of the project configuration are applied to the generator.
+func (f Fragment) generatorParam(name string, defaults v1.DefaultsConfig) (string, gogenerate.GeneratorParam, error) {
+	genCfg := f.Generators[name]
+	fullName := FragmentGeneratorName(f.Dir, name)
+
+	var err error
+	resolve := func(field, p string) string {
+		resolved := path.Join(f.Dir, p)
+		if resolved != f.Dir && !strings.HasPrefix(resolved, f.Dir+"/") && err == nil {
+			err = errors.Errorf("invalid configuration fragment %s: %s %q of generator %q is outside of the directory of the fragment", f.File, field, p, name)
+		}
+		return resolved
+	}
+	resolveAll := func(field string, paths []string) []string {
+		var resolved []string
+		for _, p := range paths {
+			resolved = append(resolved, resolve(field, p))
+		}
+		return resolved
+	}
+	genCfg.GoGenDir = resolve("go-generate-dir", genCfg.GoGenDir)
+	genCfg.GenPaths.Paths = resolveAll("gen-paths path", genCfg.GenPaths.Paths)
+	genCfg.Exclude.Paths = resolveAll("exclude path", genCfg.Exclude.Paths)
+	genCfg.Inputs.Paths = resolveAll("inputs path", genCfg.Inputs.Paths)
+	if err != nil {
+		return "", gogenerate.GeneratorParam{}, err
+	}
+	// defaults are applied after the paths of the fragment are resolved because the default exclude paths are relative
+	// to the project directory
+	genCfg = genCfg.withDefaults(defaults)
+	var dependsOn []string
+	for _, dep := range genCfg.DependsOn {
+		dependsOn = append(dependsOn, f.dependencyName(dep))
+	}
+	genCfg.DependsOn = dependsOn
+
+	genParam, err := genCfg.toParam()
+	if err != nil {
+		return "", gogenerate.GeneratorParam{}, errors.Wrapf(err, "invalid configuration fragment %s: invalid configuration of generator %q", f.File, name)
+	}
+	if len(genCfg.GenPaths.Names) > 0 {
+		genPaths := matcher.Any(matcher.Path(genCfg.GenPaths.Paths...), dirMatcher{dir: f.Dir, matcher: matcher.Name(genCfg.GenPaths.Names...)})
+		genParam.GenPaths = withExclude(genPaths, genCfg.Exclude, genCfg.Exclude.Matcher())
+		// names only match paths in the directory of the fragment, so the rest of the project is not walked
+		genParam.GenPathRoots = []string{f.Dir}
+	}
+	if len(genCfg.Inputs.Names) > 0 {
+		genParam.Inputs = matcher.Any(matcher.Path(genCfg.Inputs.Paths...), dirMatcher{dir: f.Dir, matcher: matcher.Name(genCfg.Inputs.Names...)})
+	}
+	return fullName, genParam, nil
+}
+
+// dependencyName returns the full name of the generator referred to by the provided depends-on entry of a generator in
+// the fragment (see generatorParam).
+func (f Fragment) dependencyName(dep string) string {
+	switch {
+	case strings.HasPrefix(dep, ":"):
+		return strings.TrimPrefix(dep, ":")
+	case strings.Contains(dep, ":"):
+		return dep
+	default:
+		return FragmentGeneratorName(f.Dir, dep)
+	}
+}
+
+// dirMatcher matches the paths in a directory that are matched by another matcher.
+type dirMatcher struct {
+	// dir is the slash-separated path of the directory relative to the project directory.
+	dir     string
+	matcher matcher.Matcher
+}
+
+func (m dirMatcher) Match(relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	return strings.HasPrefix(relPath, m.dir+"/") && m.matcher.Match(relPath)
+}
+
+// isSameFile returns true if the provided paths refer to the same existing file.
+func isSameFile(a, b string) bool {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(aInfo, bInfo)
+}
+
+func sortedGeneratorNames[V any](generators map[string]V) []string {
+	var names []string
+	for k := range generators {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,314 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config_test
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/palantir/go-generate/gogenerate"
+	"github.com/palantir/go-generate/gogenerate/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestToParamWithFragments(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	writeFiles(t, tmpDir, map[string]string{
+		"generate.yml": `version: 1
+discover-fragments: true
+defaults:
+  environment:
+    GOFLAGS: -mod=mod
+  exclude:
+    paths:
+      - "teams/a/proto/generated/static"
+generators:
+  root-gen:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "gen/output.txt"
+`,
+		"teams/a/generate.yml": `version: 1
+generators:
+  proto:
+    go-generate-dir: proto
+    gen-paths:
+      paths:
+        - "proto/generated"
+      names:
+        - ".+\\.pb\\.go"
+    exclude:
+      paths:
+        - "proto/generated/doc.go"
+  mocks:
+    go-generate-dir: mocks
+    gen-paths:
+      paths:
+        - "mocks/mocks.go"
+    depends-on:
+      - proto
+      - ":root-gen"
+`,
+		// legacy and v0 fragments are supported
+		"teams/b/generate.yml": `generators:
+  gen:
+    gen-paths:
+      paths:
+        - "output.txt"
+`,
+		"vendor/github.com/foo/generate.yml": `invalid`,
+		".hidden/generate.yml":               `invalid`,
+	})
+
+	projectParam, err := loadFragmentsConfig(t, tmpDir, path.Join(tmpDir, "generate.yml"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"root-gen", "teams/a:mocks", "teams/a:proto", "teams/b:gen"}, projectParam.Generators.SortedKeys())
+
+	proto := projectParam.Generators["teams/a:proto"]
+	assert.Equal(t, "teams/a/proto", proto.GoGenDir)
+	assert.True(t, proto.GenPaths.Match("teams/a/proto/generated/foo.go"))
+	assert.True(t, proto.GenPaths.Match("teams/a/other/foo.pb.go"))
+	assert.False(t, proto.GenPaths.Match("teams/b/foo.pb.go"))
+	assert.False(t, proto.GenPaths.Match("proto/generated/foo.go"))
+	// exclude paths of the fragment are relative to the fragment, while default exclude paths are relative to the project
+	assert.False(t, proto.GenPaths.Match("teams/a/proto/generated/doc.go"))
+	assert.False(t, proto.GenPaths.Match("teams/a/proto/generated/static/foo.go"))
+	assert.Equal(t, []string{"teams/a"}, proto.GenPathRoots)
+
+	mocks := projectParam.Generators["teams/a:mocks"]
+	assert.Equal(t, []string{"teams/a:proto", "root-gen"}, mocks.DependsOn)
+	assert.Equal(t, []string{"teams/a/mocks/mocks.go"}, mocks.GenPathRoots)
+
+	gen := projectParam.Generators["teams/b:gen"]
+	assert.Equal(t, "teams/b", gen.GoGenDir)
+	assert.Equal(t, map[string]string{"GOFLAGS": "-mod=mod"}, gen.Environment)
+	assert.True(t, gen.GenPaths.Match("teams/b/output.txt"))
+
+	order, err := projectParam.Generators.ExecutionOrder()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"root-gen", "teams/a:proto", "teams/a:mocks", "teams/b:gen"}, order)
+}
+
+func TestToParamWithFragmentsDisabled(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	writeFiles(t, tmpDir, map[string]string{
+		"generate.yml": `version: 1
+generators:
+  root-gen:
+    go-generate-dir: gen
+`,
+		"teams/a/generate.yml": `version: 1
+generators:
+  proto:
+    go-generate-dir: proto
+`,
+	})
+	projectParam, err := loadFragmentsConfig(t, tmpDir, path.Join(tmpDir, "generate.yml"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"root-gen"}, projectParam.Generators.SortedKeys())
+}
+
+func TestToParamWithFragmentsErrors(t *testing.T) {
+	for currCaseNum, currCase := range []struct {
+		name      string
+		rootGens  string
+		fragment  string
+		wantError string
+	}{
+		{
+			name: "generator names conflict",
+			rootGens: `
+  "teams/a:proto":
+    go-generate-dir: gen
+`,
+			fragment: `
+  proto:
+    go-generate-dir: proto
+`,
+			wantError: `generator "teams/a:proto" in teams/a/generate.yml conflicts with the generator of the same name in {{cfg}}`,
+		},
+		{
+			name: "gen-paths conflict",
+			rootGens: `
+  root-gen:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "teams/a/proto/generated"
+`,
+			fragment: `
+  proto:
+    go-generate-dir: proto
+    gen-paths:
+      paths:
+        - "proto/generated/"
+`,
+			wantError: `gen-paths of generator "teams/a:proto" in teams/a/generate.yml overlap with the gen-paths of generator "root-gen" in {{cfg}}: path "teams/a/proto/generated" is matched by both`,
+		},
+		{
+			name: "gen-paths path in directory of other gen-paths path",
+			rootGens: `
+  root-gen:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "teams/a"
+`,
+			fragment: `
+  proto:
+    go-generate-dir: proto
+    gen-paths:
+      paths:
+        - "proto/generated/foo.go"
+`,
+			wantError: `gen-paths of generator "teams/a:proto" in teams/a/generate.yml overlap with the gen-paths of generator "root-gen" in {{cfg}}: path "teams/a/proto/generated/foo.go" is matched by both`,
+		},
+		{
+			name: "gen-paths path contains other gen-paths path",
+			rootGens: `
+  root-gen:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "teams/a/proto/generated/foo.go"
+`,
+			fragment: `
+  proto:
+    go-generate-dir: proto
+    gen-paths:
+      paths:
+        - "proto"
+`,
+			wantError: `gen-paths of generator "teams/a:proto" in teams/a/generate.yml overlap with the gen-paths of generator "root-gen" in {{cfg}}: path "teams/a/proto/generated/foo.go" is matched by both`,
+		},
+		{
+			name: "gen-paths path matched by gen-paths name",
+			rootGens: `
+  root-gen:
+    go-generate-dir: gen
+    gen-paths:
+      names:
+        - ".+\\.pb\\.go"
+`,
+			fragment: `
+  proto:
+    go-generate-dir: proto
+    gen-paths:
+      paths:
+        - "proto/foo.pb.go"
+`,
+			wantError: `gen-paths of generator "teams/a:proto" in teams/a/generate.yml overlap with the gen-paths of generator "root-gen" in {{cfg}}: path "teams/a/proto/foo.pb.go" is matched by both`,
+		},
+		{
+			name: "gen-paths names conflict",
+			rootGens: `
+  root-gen:
+    go-generate-dir: gen
+    gen-paths:
+      names:
+        - ".+\\.pb\\.go"
+`,
+			fragment: `
+  proto:
+    go-generate-dir: proto
+    gen-paths:
+      names:
+        - ".+\\.pb\\.go"
+`,
+			wantError: `gen-paths of generator "teams/a:proto" in teams/a/generate.yml overlap with the gen-paths of generator "root-gen" in {{cfg}}: name ".+\\.pb\\.go" is specified by both`,
+		},
+		{
+			name: "path outside of fragment",
+			fragment: `
+  proto:
+    go-generate-dir: proto
+    gen-paths:
+      paths:
+        - "../b/generated"
+`,
+			wantError: `invalid configuration fragment teams/a/generate.yml: gen-paths path "../b/generated" of generator "proto" is outside of the directory of the fragment`,
+		},
+		{
+			name: "fragment specifies more than generators",
+			fragment: `
+  proto:
+    go-generate-dir: proto
+exclude:
+  names:
+    - "vendor"
+`,
+			wantError: `failed to discover configuration fragments: invalid configuration fragment teams/a/generate.yml: fragments may only specify generators`,
+		},
+		{
+			name: "unknown key in fragment",
+			fragment: `
+  proto:
+    go-generate-dir: proto
+    gen-path:
+      paths:
+        - "proto/generated/"
+`,
+			wantError: `failed to discover configuration fragments: teams/a/generate.yml:5:5: unknown key "gen-path" in generators.proto`,
+		},
+	} {
+		tmpDir, cleanup, err := dirs.TempDir("", "")
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		cfgFile := path.Join(tmpDir, "godel", "config", "generate.yml")
+		writeFiles(t, tmpDir, map[string]string{
+			// project configuration in a subdirectory is not a fragment
+			"godel/config/generate.yml": "version: 1\ndiscover-fragments: true\ngenerators:" + currCase.rootGens + "\n",
+			"teams/a/generate.yml":      "version: 1\ngenerators:" + currCase.fragment,
+		})
+
+		_, err = loadFragmentsConfig(t, tmpDir, cfgFile)
+		assert.EqualError(t, err, strings.ReplaceAll(currCase.wantError, "{{cfg}}", cfgFile), "Case %d: %s", currCaseNum, currCase.name)
+
+		cleanup()
+	}
+}
+
+func loadFragmentsConfig(t *testing.T, rootDir, cfgFile string) (gogenerate.ProjectParam, error) {
+	cfgBytes, err := os.ReadFile(cfgFile)
+	require.NoError(t, err)
+	upgradedCfg, err := config.UpgradeConfig(cfgBytes)
+	require.NoError(t, err)
+	var cfg config.ProjectConfig
+	err = yaml.Unmarshal(upgradedCfg, &cfg)
+	require.NoError(t, err)
+	return cfg.ToParamWithFragments(rootDir, cfgFile)
+}
+
+func writeFiles(t *testing.T, rootDir string, files map[string]string) {
+	for relPath, content := range files {
+		err := os.MkdirAll(path.Dir(path.Join(rootDir, relPath)), 0755)
+		require.NoError(t, err)
+		err = os.WriteFile(path.Join(rootDir, relPath), []byte(content), 0644)
+		require.NoError(t, err)
+	}
+}
@@ -0,0 +1,316 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"regexp"
+	"time"
+
+	v0 "github.com/palantir/go-generate/gogenerate/config/internal/v0"
+	"github.com/palantir/godel/v2/pkg/versionedconfig"
+	"github.com/palantir/pkg/matcher"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+type ProjectConfig struct {
+	versionedconfig.ConfigWithVersion `yaml:",inline"`
+	// Generators is a map from the name of a generator to its configuration.
+	Generators map[string]GeneratorConfig `yaml:"generators,omitempty"`
+	// Exclude specifies the files and directories that are never considered to be the output of any generator.
+	// Excluded directories are not walked when computing the checksums of generated paths, so excluding large
+	// directories such as "vendor" or "node_modules" can make runs substantially faster.
+	Exclude matcher.NamesPathsCfg `yaml:"exclude,omitempty"`
+	// ScanErrors specifies how errors encountered while computing the checksums of generated paths are handled.
+	ScanErrors ScanErrorsConfig `yaml:"scan-errors,omitempty"`
+	// CoverageIgnore specifies the directories and files whose go:generate directives are intentionally not run by any
+	// generator. Directives matched by CoverageIgnore are not reported by the coverage check.
+	CoverageIgnore matcher.NamesPathsCfg `yaml:"coverage-ignore,omitempty"`
+	// DiscoverFragments specifies whether the configurations of generators are also read from the "generate.yml" files
+	// in the subdirectories of the project. The paths in a fragment are relative to the directory that contains it and
+	// the names of its generators are prefixed with the path of the directory, so every team can own the configuration
+	// of the generators of its packages.
+	DiscoverFragments bool `yaml:"discover-fragments,omitempty"`
+	// Defaults specifies the configuration that is inherited by every generator.
+	Defaults DefaultsConfig `yaml:"defaults,omitempty"`
+}
+
+// DefaultsConfig specifies the configuration that is inherited by every generator. The environment variables of a
+// generator are merged with the default ones, and a variable specified by a generator overrides the default value. The
+// exclude names and paths of a generator are added to the default ones.
+// Every other setting of a generator overrides the default one if it is specified, even if it is specified as false or
+// as an empty list. For example, a generator that specifies "fold-crlf: false" does not fold line endings even if
+// they are folded by default, and the ignore-lines expressions of a generator replace the default ones.
+type DefaultsConfig struct {
+	// Environment specifies the default values of environment variables that should be set for every generator.
+	Environment map[string]string `yaml:"environment,omitempty"`
+	// Flags specifies the default additional arguments that are provided to "go generate" (or to the command of a
+	// generator).
+	Flags []string `yaml:"flags,omitempty"`
+	// Exclude specifies the files and directories matched by the gen-paths of every generator that are not generated by
+	// the generator.
+	Exclude matcher.NamesPathsCfg `yaml:"exclude,omitempty"`
+	// Timeout specifies the default maximum duration of a run of a generator.
+	Timeout string `yaml:"timeout,omitempty"`
+	// IgnoreMode specifies whether changes to the permission bits of generated paths are ignored for every generator.
+	IgnoreMode *bool `yaml:"ignore-mode,omitempty"`
+	// Normalize specifies the transformations applied to the content of the files matched by the gen-paths of every
+	// generator.
+	Normalize NormalizeConfig `yaml:"normalize,omitempty"`
+	// OutputChecks specifies the checks that are performed on the Go files matched by the gen-paths of every
+	// generator.
+	OutputChecks OutputChecksConfig `yaml:"output-checks,omitempty"`
+}
+
+func (cfg *DefaultsConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type defaultsConfigAlias DefaultsConfig
+	var alias defaultsConfigAlias
+	if err := unmarshal(&alias); err != nil {
+		return err
+	}
+	if _, err := ParseTimeout(alias.Timeout); err != nil {
+		return err
+	}
+	*cfg = DefaultsConfig(alias)
+	return nil
+}
+
+// ScanErrorsConfig specifies the action taken for every class of error that can be encountered while computing the
+// checksums of generated paths. Valid actions are "fail", "warn" and "skip". If an action is not specified, it
+// defaults to "fail". Paths for which errors are skipped or warned about are treated as if they did not exist. For
+// example:
+//
+//	scan-errors:
+//	  permission-denied: warn
+//	  not-exist: skip
+type ScanErrorsConfig struct {
+	// PermissionDenied is the action taken when a directory or file cannot be read because of its permissions.
+	PermissionDenied string `yaml:"permission-denied,omitempty"`
+	// NotExist is the action taken when a path is removed while it is being scanned.
+	NotExist string `yaml:"not-exist,omitempty"`
+}
+
+func (cfg *ScanErrorsConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type scanErrorsConfigAlias ScanErrorsConfig
+	var alias scanErrorsConfigAlias
+	if err := unmarshal(&alias); err != nil {
+		return err
+	}
+	for _, action := range []string{alias.PermissionDenied, alias.NotExist} {
+		switch action {
+		case "", "fail", "warn", "skip":
+		default:
+			return errors.Errorf(`invalid scan error action %q: must be one of "fail", "warn" or "skip"`, action)
+		}
+	}
+	*cfg = ScanErrorsConfig(alias)
+	return nil
+}
+
+type GeneratorConfig struct {
+	// GoGenDir is the relative path to the directory in which the generator is run.
+	GoGenDir string `yaml:"go-generate-dir,omitempty"`
+	// Command specifies the command that is run in GoGenDir instead of "go generate". The first element is the
+	// executable and the remaining elements are its arguments. For example, the following would run "buf generate":
+	//
+	//   command: [buf, generate]
+	Command []string `yaml:"command,omitempty"`
+	// Flags specifies additional arguments that are provided to "go generate" (or to Command if it is specified). For
+	// example, the following would only run the directives that invoke stringer:
+	//
+	//   flags: ["-run", "stringer"]
+	Flags []string `yaml:"flags,omitempty"`
+	// GenPaths is the configuration that specifies the criteria for matching the output files and directories
+	// generated by the generator. Any file or directory that is matched by the matchers are used to determine whether
+	// or not running the generator caused any changes.
+	GenPaths matcher.NamesPathsCfg `yaml:"gen-paths,omitempty"`
+	// Exclude specifies the files and directories matched by GenPaths that are not generated by the generator. For
+	// example, the following would match the paths in "generated" other than the hand-written "doc.go" files:
+	//
+	//   gen-paths:
+	//     paths: [generated]
+	//   exclude:
+	//     names: [doc.go]
+	Exclude matcher.NamesPathsCfg `yaml:"exclude,omitempty"`
+	// Environment specifies values for the environment variables that should be set for the generator. For example, the
+	// following would set GOOS to "darwin" and GOARCH to "amd64":
+	//
+	//   environment:
+	//     GOOS: darwin
+	//     GOARCH: amd64
+	Environment map[string]string `yaml:"environment,omitempty"`
+	// Inputs specifies the files and directories outside of GoGenDir that are read by the generator. When running in
+	// watch mode, changes to the files in GoGenDir or to the paths matched by Inputs cause the generator to be run.
+	Inputs matcher.NamesPathsCfg `yaml:"inputs,omitempty"`
+	// DependsOn specifies the names of the generators that must be run before this generator. Generators that are not
+	// linked by dependencies are run in lexicographical order of their names.
+	DependsOn []string `yaml:"depends-on,omitempty"`
+	// Timeout specifies the maximum duration of a run of the generator in the format accepted by time.ParseDuration
+	// (for example, "5m"). If the generator runs for longer, it is stopped and the run fails. By default, a run of the
+	// generator does not time out.
+	Timeout string `yaml:"timeout,omitempty"`
+	// Tags specifies labels that describe the generator. Tags do not affect how the generator is run.
+	Tags []string `yaml:"tags,omitempty"`
+	// IgnoreMode specifies whether changes to the permission bits of the paths matched by GenPaths should be ignored
+	// when verifying the output of the generator. By default, a change in permissions is reported as a difference.
+	IgnoreMode *bool `yaml:"ignore-mode,omitempty"`
+	// Normalize specifies the transformations applied to the content of the files matched by GenPaths before the
+	// content is compared. This allows the output of generators that embed volatile content such as timestamps or
+	// version banners to be verified.
+	Normalize NormalizeConfig `yaml:"normalize,omitempty"`
+	// OutputChecks specifies the checks that are performed on the Go files matched by GenPaths after the generator is
+	// run.
+	OutputChecks OutputChecksConfig `yaml:"output-checks,omitempty"`
+}
+
+func (cfg *GeneratorConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type generatorConfigAlias GeneratorConfig
+	var alias generatorConfigAlias
+	if err := unmarshal(&alias); err != nil {
+		return err
+	}
+	if alias.Command != nil && len(alias.Command) == 0 {
+		return errors.Errorf("command must not be empty")
+	}
+	if _, err := ParseTimeout(alias.Timeout); err != nil {
+		return err
+	}
+	*cfg = GeneratorConfig(alias)
+	return nil
+}
+
+// ParseTimeout returns the duration specified by the provided timeout, which must be positive. Returns 0 if the timeout
+// is empty.
+func ParseTimeout(timeoutStr string) (time.Duration, error) {
+	if timeoutStr == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(timeoutStr)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid timeout %q", timeoutStr)
+	}
+	if timeout <= 0 {
+		return 0, errors.Errorf("invalid timeout %q: must be positive", timeoutStr)
+	}
+	return timeout, nil
+}
+
+// NormalizeConfig specifies the transformations applied to the content of generated files before it is compared.
+// Line endings are folded first, then trailing whitespace is trimmed and finally ignored lines are removed. For
+// example:
+//
+//	normalize:
+//	  ignore-lines:
+//	    - "^// Generated by protoc-gen-foo v[0-9.]+ at .*$"
+//	  fold-crlf: true
+//	  trim-trailing-whitespace: true
+type NormalizeConfig struct {
+	// IgnoreLines contains regular expressions. Lines that match any of the expressions are removed.
+	IgnoreLines []string `yaml:"ignore-lines,omitempty"`
+	// FoldCRLF specifies whether CRLF line endings are converted to LF.
+	FoldCRLF *bool `yaml:"fold-crlf,omitempty"`
+	// TrimTrailingWhitespace specifies whether trailing whitespace is removed from every line.
+	TrimTrailingWhitespace *bool `yaml:"trim-trailing-whitespace,omitempty"`
+}
+
+func (cfg *NormalizeConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type normalizeConfigAlias NormalizeConfig
+	var alias normalizeConfigAlias
+	if err := unmarshal(&alias); err != nil {
+		return err
+	}
+	if _, err := NormalizeConfig(alias).IgnoreLinesRegexps(); err != nil {
+		return err
+	}
+	*cfg = NormalizeConfig(alias)
+	return nil
+}
+
+// IgnoreLinesRegexps returns the compiled IgnoreLines expressions. Returns an error if any of them is not a valid
+// regular expression.
+func (cfg NormalizeConfig) IgnoreLinesRegexps() ([]*regexp.Regexp, error) {
+	var ignoreLines []*regexp.Regexp
+	for _, expr := range cfg.IgnoreLines {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid ignore-lines regular expression %q", expr)
+		}
+		ignoreLines = append(ignoreLines, re)
+	}
+	return ignoreLines, nil
+}
+
+type OutputChecksConfig struct {
+	// RequireGeneratedHeader specifies whether every Go file matched by GenPaths must have the standard
+	// "// Code generated ... DO NOT EDIT." header after the generator is run. If true, running the generator fails if
+	// any matched Go file does not have the header.
+	RequireGeneratedHeader *bool `yaml:"require-generated-header,omitempty"`
+	// Gofmt specifies how the Go files matched by GenPaths are validated with gofmt after the generator is run. If
+	// "check", running the generator fails if any matched Go file is not formatted or is not valid Go. If "fix", matched
+	// Go files that are not formatted are formatted and running the generator fails if any of them is not valid Go. By
+	// default, matched Go files are not validated.
+	Gofmt string `yaml:"gofmt,omitempty"`
+}
+
+func (cfg *OutputChecksConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type outputChecksConfigAlias OutputChecksConfig
+	var alias outputChecksConfigAlias
+	if err := unmarshal(&alias); err != nil {
+		return err
+	}
+	switch alias.Gofmt {
+	case "", "check", "fix":
+	default:
+		return errors.Errorf(`invalid gofmt action %q: must be one of "check" or "fix"`, alias.Gofmt)
+	}
+	*cfg = OutputChecksConfig(alias)
+	return nil
+}
+
+// UpgradeV0Config upgrades the provided v0 configuration to v1. The fields of v0 generators are unchanged in v1.
+func UpgradeV0Config(v0Bytes []byte) ([]byte, error) {
+	var v0Cfg v0.ProjectConfig
+	if err := yaml.UnmarshalStrict(v0Bytes, &v0Cfg); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal generate-plugin v0 configuration")
+	}
+	cfg := ProjectConfig{
+		ConfigWithVersion: versionedconfig.ConfigWithVersion{
+			Version: "1",
+		},
+	}
+	if v0Cfg.Generators != nil {
+		cfg.Generators = make(map[string]GeneratorConfig, len(v0Cfg.Generators))
+	}
+	for k, v := range v0Cfg.Generators {
+		cfg.Generators[k] = GeneratorConfig{
+			GoGenDir:    v.GoGenDir,
+			GenPaths:    v.GenPaths,
+			Environment: v.Environment,
+		}
+	}
+	upgradedBytes, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal generate-plugin v1 configuration")
+	}
+	return upgradedBytes, nil
+}
+
+func UpgradeConfig(cfgBytes []byte) ([]byte, error) {
+	var cfg ProjectConfig
+	if err := yaml.UnmarshalStrict(cfgBytes, &cfg); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal generate-plugin v1 configuration")
+	}
+	return cfgBytes, nil
+}
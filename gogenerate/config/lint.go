@@ -0,0 +1,254 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/palantir/go-generate/gogenerate"
+	"github.com/pkg/errors"
+	yamlv3 "go.yaml.in/yaml/v3"
+	"gopkg.in/yaml.v2"
+)
+
+// Problem is a problem with a configuration found by Lint.
+type Problem struct {
+	// File is the slash-separated path relative to the project directory of the configuration fragment that has the
+	// problem. Empty if the problem is in the project configuration.
+	File string
+	// Generator is the name of the generator that has the problem. Empty if the problem is not specific to a generator.
+	Generator string
+	// Line is the 1-based line number of the configuration that has the problem. 0 if the line is not known.
+	Line int
+	// Message describes the problem.
+	Message string
+}
+
+// Location returns the location of the problem in the form "file:line", where the file is the provided project
+// configuration file unless the problem is in a configuration fragment.
+func (p Problem) Location(cfgFile string) string {
+	if p.File != "" {
+		cfgFile = p.File
+	}
+	if p.Line == 0 {
+		return cfgFile
+	}
+	return fmt.Sprintf("%s:%d", cfgFile, p.Line)
+}
+
+func (p Problem) String() string {
+	if p.Generator == "" {
+		return p.Message
+	}
+	return fmt.Sprintf("generator %q: %s", p.Generator, p.Message)
+}
+
+// Lint returns the problems with the provided configuration, which may be of any supported version and was read from
+// the provided file, for the project in the provided directory. If the configuration specifies discover-fragments, the
+// generators of its configuration fragments are linted as well and may be referred to by the dependencies of other
+// generators. Lint examines the configuration and the files in the project but does not run any generators. The
+// following are reported as problems:
+//
+//   - a "go-generate-dir" that does not exist, is not a directory or does not contain any go:generate directives (unless
+//     the generator specifies its own "command")
+//   - a path in "gen-paths" that does not match any existing path
+//   - a generator in "depends-on" that does not exist, or dependencies that contain a cycle
+//
+// The problems of the project configuration are returned first, followed by the problems of every fragment in order of
+// path, and the problems of every file are sorted by line. Returns an error if the configuration or any of its fragments
+// cannot be parsed or if the generators of a fragment conflict with other generators.
+func Lint(rootDir, cfgFile string, cfgBytes []byte) ([]Problem, error) {
+	upgradedCfg, err := UpgradeConfig(cfgBytes)
+	if err != nil {
+		return nil, err
+	}
+	var cfg ProjectConfig
+	if err := yaml.Unmarshal(upgradedCfg, &cfg); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal go-generate configuration")
+	}
+	generatorsKey, generatorsNode, err := generatorsEntry(cfgBytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse go-generate configuration")
+	}
+	projectParam, err := cfg.ToParamWithFragments(rootDir, cfgFile)
+	if err != nil {
+		return nil, err
+	}
+	var fragments []Fragment
+	if cfg.DiscoverFragments {
+		if fragments, err = Fragments(rootDir, cfgFile, projectParam.Exclude); err != nil {
+			return nil, err
+		}
+	}
+
+	l := linter{
+		rootDir:    rootDir,
+		generators: projectParam.Generators,
+	}
+	rootGenerators := make(map[string]GeneratorConfig, len(cfg.Generators))
+	for k, v := range cfg.Generators {
+		rootGenerators[k] = GeneratorConfig(v)
+	}
+	l.lintGenerators(rootGenerators, generatorsNode, Fragment{Dir: "."})
+	for _, fragment := range fragments {
+		fragmentBytes, err := os.ReadFile(filepath.Join(rootDir, filepath.FromSlash(fragment.File)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read configuration fragment %s", fragment.File)
+		}
+		_, fragmentGeneratorsNode, err := generatorsEntry(fragmentBytes)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse configuration fragment %s", fragment.File)
+		}
+		l.lintGenerators(fragment.Generators, fragmentGeneratorsNode, fragment)
+	}
+
+	if !l.unknownDependency {
+		// unknown dependencies are already reported above, so only check for cycles
+		if _, err := projectParam.Generators.ExecutionOrder(); err != nil {
+			l.problems = append(l.problems, Problem{
+				Line:    nodeLine(generatorsKey),
+				Message: err.Error(),
+			})
+		}
+	}
+	sort.SliceStable(l.problems, func(i, j int) bool {
+		if l.problems[i].File != l.problems[j].File {
+			return l.problems[i].File < l.problems[j].File
+		}
+		return l.problems[i].Line < l.problems[j].Line
+	})
+	return l.problems, nil
+}
+
+// generatorsEntry returns the key and value nodes of the "generators" entry of the provided configuration.
+func generatorsEntry(cfgBytes []byte) (*yamlv3.Node, *yamlv3.Node, error) {
+	var root yamlv3.Node
+	if err := yamlv3.Unmarshal(cfgBytes, &root); err != nil {
+		return nil, nil, err
+	}
+	key, value := mappingEntry(documentContent(&root), "generators")
+	return key, value, nil
+}
+
+type linter struct {
+	rootDir string
+	// generators are all of the generators of the project keyed by their full names
+	generators        gogenerate.Generators
+	problems          []Problem
+	unknownDependency bool
+}
+
+// lintGenerators adds the problems of the provided generators, which are specified by the provided "generators" node of
+// the provided fragment. The generators of the project configuration are linted as the generators of a fragment whose
+// File is empty and whose Dir is ".".
+func (l *linter) lintGenerators(generators map[string]GeneratorConfig, generatorsNode *yamlv3.Node, fragment Fragment) {
+	for _, name := range sortedGeneratorNames(generators) {
+		genCfg := generators[name]
+		fullName := name
+		if fragment.File != "" {
+			fullName = FragmentGeneratorName(fragment.Dir, name)
+		}
+		nameKey, genNode := mappingEntry(generatorsNode, name)
+		addProblem := func(node *yamlv3.Node, format string, args ...interface{}) {
+			if node == nil {
+				node = nameKey
+			}
+			l.problems = append(l.problems, Problem{
+				File:      fragment.File,
+				Generator: fullName,
+				Line:      nodeLine(node),
+				Message:   fmt.Sprintf(format, args...),
+			})
+		}
+
+		dirKey, _ := mappingEntry(genNode, "go-generate-dir")
+		goGenDir := path.Join(fragment.Dir, genCfg.GoGenDir)
+		if fi, err := os.Stat(filepath.Join(l.rootDir, goGenDir)); os.IsNotExist(err) {
+			addProblem(dirKey, "go-generate-dir %q does not exist", genCfg.GoGenDir)
+		} else if err != nil {
+			addProblem(dirKey, "go-generate-dir %q cannot be read: %v", genCfg.GoGenDir, err)
+		} else if !fi.IsDir() {
+			addProblem(dirKey, "go-generate-dir %q is not a directory", genCfg.GoGenDir)
+		} else if directives, err := gogenerate.DirDirectives(l.rootDir, goGenDir); err != nil {
+			addProblem(dirKey, "go-generate-dir %q cannot be read: %v", genCfg.GoGenDir, err)
+		} else if len(directives) == 0 && len(genCfg.Command) == 0 {
+			addProblem(dirKey, "go-generate-dir %q does not contain any go:generate directives", genCfg.GoGenDir)
+		}
+
+		_, genPathsNode := mappingEntry(genNode, "gen-paths")
+		_, pathsNode := mappingEntry(genPathsNode, "paths")
+		for i, p := range genCfg.GenPaths.Paths {
+			itemNode := sequenceItem(pathsNode, i)
+			if matches, err := filepath.Glob(filepath.Join(l.rootDir, fragment.Dir, p)); err != nil {
+				addProblem(itemNode, "gen-paths path %q is not a valid pattern: %v", p, err)
+			} else if len(matches) == 0 {
+				addProblem(itemNode, "gen-paths path %q does not match any existing path", p)
+			}
+		}
+
+		_, dependsOnNode := mappingEntry(genNode, "depends-on")
+		for i, dep := range genCfg.DependsOn {
+			depName := dep
+			if fragment.File != "" {
+				depName = fragment.dependencyName(dep)
+			}
+			if _, ok := l.generators[depName]; !ok {
+				l.unknownDependency = true
+				addProblem(sequenceItem(dependsOnNode, i), "depends-on generator %q does not exist", dep)
+			}
+		}
+	}
+}
+
+func documentContent(node *yamlv3.Node) *yamlv3.Node {
+	if node.Kind == yamlv3.DocumentNode && len(node.Content) > 0 {
+		return node.Content[0]
+	}
+	return node
+}
+
+// mappingEntry returns the key and value nodes of the entry with the provided key in the provided mapping node.
+// Returns nil nodes if the provided node is nil or not a mapping or if it does not contain the key.
+func mappingEntry(node *yamlv3.Node, key string) (*yamlv3.Node, *yamlv3.Node) {
+	if node == nil || node.Kind != yamlv3.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// sequenceItem returns the item at the provided index of the provided sequence node. Returns the sequence node itself
+// if it is not a sequence or does not contain the index.
+func sequenceItem(node *yamlv3.Node, i int) *yamlv3.Node {
+	if node == nil || node.Kind != yamlv3.SequenceNode || i >= len(node.Content) {
+		return node
+	}
+	return node.Content[i]
+}
+
+func nodeLine(node *yamlv3.Node) int {
+	if node == nil {
+		return 0
+	}
+	return node.Line
+}
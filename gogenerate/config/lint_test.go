@@ -0,0 +1,167 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config_test
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/nmiyake/pkg/gofiles"
+	"github.com/palantir/go-generate/gogenerate/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "gen/gen.go",
+			Src: `package gen
+
+//go:generate go run generator_main.go
+`,
+		},
+		{
+			RelPath: "gen/output.txt",
+		},
+		{
+			RelPath: "nodirectives/foo.go",
+			Src:     "package nodirectives\n",
+		},
+		{
+			RelPath: "teams/a/gen/gen.go",
+			Src: `package gen
+
+//go:generate go run generator_main.go
+`,
+		},
+	})
+	require.NoError(t, err)
+	err = os.WriteFile(path.Join(tmpDir, "file.txt"), nil, 0644)
+	require.NoError(t, err)
+	err = os.WriteFile(path.Join(tmpDir, "teams", "a", config.FragmentFileName), []byte(`version: 1
+generators:
+  proto:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - gen/missing.txt
+    depends-on:
+      - :foo
+      - missing
+`), 0644)
+	require.NoError(t, err)
+
+	for currCaseNum, currCase := range []struct {
+		name string
+		cfg  string
+		want []config.Problem
+	}{
+		{
+			name: "valid configuration has no problems",
+			cfg: `generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - gen/*.txt
+`,
+		},
+		{
+			name: "problems are reported with their generator and line",
+			cfg: `version: 1
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - gen/output.txt
+        - gen/missing.txt
+    depends-on:
+      - missing
+  bar:
+    go-generate-dir: deleted
+  baz:
+    go-generate-dir: nodirectives
+  qux:
+    go-generate-dir: file.txt
+`,
+			want: []config.Problem{
+				{Generator: "foo", Line: 8, Message: `gen-paths path "gen/missing.txt" does not match any existing path`},
+				{Generator: "foo", Line: 10, Message: `depends-on generator "missing" does not exist`},
+				{Generator: "bar", Line: 12, Message: `go-generate-dir "deleted" does not exist`},
+				{Generator: "baz", Line: 14, Message: `go-generate-dir "nodirectives" does not contain any go:generate directives`},
+				{Generator: "qux", Line: 16, Message: `go-generate-dir "file.txt" is not a directory`},
+			},
+		},
+		{
+			name: "dependency cycles are reported",
+			cfg: `version: 1
+generators:
+  foo:
+    go-generate-dir: gen
+    depends-on:
+      - bar
+  bar:
+    go-generate-dir: gen
+    depends-on:
+      - foo
+`,
+			want: []config.Problem{
+				{Line: 2, Message: "generators [bar foo] cannot be ordered because their dependencies contain a cycle"},
+			},
+		},
+		{
+			name: "fragments are linted and their generators may be dependencies",
+			cfg: `version: 1
+discover-fragments: true
+generators:
+  foo:
+    go-generate-dir: gen
+  bar:
+    go-generate-dir: gen
+    depends-on:
+      - teams/a:proto
+      - teams/a:missing
+`,
+			want: []config.Problem{
+				{Generator: "bar", Line: 10, Message: `depends-on generator "teams/a:missing" does not exist`},
+				{File: "teams/a/generate.yml", Generator: "teams/a:proto", Line: 7, Message: `gen-paths path "gen/missing.txt" does not match any existing path`},
+				{File: "teams/a/generate.yml", Generator: "teams/a:proto", Line: 10, Message: `depends-on generator "missing" does not exist`},
+			},
+		},
+		{
+			name: "legacy configuration is linted",
+			cfg: `legacy-config: true
+generators:
+  foo:
+    go-generate-dir: deleted
+`,
+			want: []config.Problem{
+				{Generator: "foo", Line: 4, Message: `go-generate-dir "deleted" does not exist`},
+			},
+		},
+	} {
+		got, err := config.Lint(tmpDir, path.Join(tmpDir, "generate.yml"), []byte(currCase.cfg))
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		assert.Equal(t, currCase.want, got, "Case %d: %s", currCaseNum, currCase.name)
+	}
+}
@@ -0,0 +1,313 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/palantir/go-generate/gogenerate/config/internal/legacy"
+	v0 "github.com/palantir/go-generate/gogenerate/config/internal/v0"
+	v1 "github.com/palantir/go-generate/gogenerate/config/internal/v1"
+	"github.com/palantir/godel/v2/pkg/versionedconfig"
+	"github.com/pkg/errors"
+	yamlv3 "go.yaml.in/yaml/v3"
+	"gopkg.in/yaml.v2"
+)
+
+// regexpFields specifies the fields whose values are regular expressions. Keys are of the form
+// "<type name>.<field name>".
+var regexpFields = map[string]bool{
+	"NamesPathsCfg.Names":         true,
+	"NormalizeConfig.IgnoreLines": true,
+}
+
+// LoadConfig returns the configuration in the provided bytes, which were read from the provided file and may be of any
+// supported version. The configuration is decoded strictly: unknown keys, values of the wrong type and invalid regular
+// expressions (such as the names of gen-paths) are reported along with the file, line and column at which they occur.
+// If the configuration has more than one such problem, all of them are reported.
+func LoadConfig(cfgFile string, cfgBytes []byte) (ProjectConfig, error) {
+	if err := checkConfig(cfgFile, cfgBytes); err != nil {
+		return ProjectConfig{}, err
+	}
+	upgradedCfg, err := UpgradeConfig(cfgBytes)
+	if err != nil {
+		return ProjectConfig{}, errors.Wrap(err, cfgFile)
+	}
+	var cfg ProjectConfig
+	if err := yaml.UnmarshalStrict(upgradedCfg, &cfg); err != nil {
+		return ProjectConfig{}, errors.Wrapf(err, "%s: failed to unmarshal go-generate configuration", cfgFile)
+	}
+	if err := cfg.Validate(); err != nil {
+		return ProjectConfig{}, errors.Wrap(err, cfgFile)
+	}
+	return cfg, nil
+}
+
+// checkConfig verifies that the keys and values of the provided configuration match the Go types of its version.
+// Configurations of unsupported versions are not checked.
+func checkConfig(cfgFile string, cfgBytes []byte) error {
+	var root yamlv3.Node
+	if err := yamlv3.Unmarshal(cfgBytes, &root); err != nil {
+		return errors.Wrap(err, cfgFile)
+	}
+	if root.Kind == 0 {
+		// empty configuration
+		return nil
+	}
+	cfgType, ok := configType(cfgBytes)
+	if !ok {
+		return nil
+	}
+	var c configChecker
+	c.check(documentContent(&root), cfgType, "")
+	if len(c.problems) == 0 {
+		return nil
+	}
+	sort.SliceStable(c.problems, func(i, j int) bool {
+		if c.problems[i].line != c.problems[j].line {
+			return c.problems[i].line < c.problems[j].line
+		}
+		return c.problems[i].column < c.problems[j].column
+	})
+	lines := make([]string, len(c.problems))
+	for i, p := range c.problems {
+		lines[i] = fmt.Sprintf("%s:%d:%d: %s", cfgFile, p.line, p.column, p.message)
+	}
+	return errors.New(strings.Join(lines, "\n"))
+}
+
+// configType returns the type into which the provided configuration is unmarshalled by UpgradeConfig. Returns false if
+// the version of the configuration is not supported.
+func configType(cfgBytes []byte) (reflect.Type, bool) {
+	if versionedconfig.IsLegacyConfig(cfgBytes) {
+		return reflect.TypeOf(legacy.GoGenerateWithLegacy{}), true
+	}
+	version, err := versionedconfig.ConfigVersion(cfgBytes)
+	if err != nil {
+		return nil, false
+	}
+	switch version {
+	case "", "0":
+		return reflect.TypeOf(v0.ProjectConfig{}), true
+	case "1":
+		return reflect.TypeOf(v1.ProjectConfig{}), true
+	default:
+		return nil, false
+	}
+}
+
+type configProblem struct {
+	line    int
+	column  int
+	message string
+}
+
+type configChecker struct {
+	problems []configProblem
+}
+
+func (c *configChecker) addProblem(node *yamlv3.Node, format string, args ...interface{}) {
+	c.problems = append(c.problems, configProblem{
+		line:    node.Line,
+		column:  node.Column,
+		message: fmt.Sprintf(format, args...),
+	})
+}
+
+// check verifies that the provided node can be unmarshalled into a value of the provided type. The provided path is
+// the dot-separated path of the node in the configuration and is used to describe problems.
+func (c *configChecker) check(node *yamlv3.Node, t reflect.Type, path string) {
+	for node.Kind == yamlv3.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yamlv3.ScalarNode && node.ShortTag() == "!!null" {
+		return
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		c.check(node, t.Elem(), path)
+	case reflect.Struct:
+		if node.Kind != yamlv3.MappingNode {
+			c.addWrongTypeProblem(node, t, path)
+			return
+		}
+		fields := make(map[string]structField)
+		for _, field := range yamlFields(t) {
+			fields[field.yamlName] = field
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			if keyNode.Value == "<<" {
+				// merged mappings must also be valid values of the struct
+				c.checkMerge(valueNode, t, path)
+				continue
+			}
+			field, ok := fields[keyNode.Value]
+			if !ok {
+				if path == "" {
+					c.addProblem(keyNode, "unknown key %q", keyNode.Value)
+				} else {
+					c.addProblem(keyNode, "unknown key %q in %s", keyNode.Value, path)
+				}
+				continue
+			}
+			fieldPath := joinConfigPath(path, keyNode.Value)
+			c.check(valueNode, field.Type, fieldPath)
+			if regexpFields[field.owner+"."+field.Name] {
+				c.checkRegexps(valueNode, fieldPath)
+			}
+		}
+	case reflect.Map:
+		if node.Kind != yamlv3.MappingNode {
+			c.addWrongTypeProblem(node, t, path)
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			c.check(keyNode, t.Key(), path)
+			c.check(valueNode, t.Elem(), joinConfigPath(path, keyNode.Value))
+		}
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yamlv3.SequenceNode {
+			c.addWrongTypeProblem(node, t, path)
+			return
+		}
+		for i, item := range node.Content {
+			c.check(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	default:
+		if node.Kind != yamlv3.ScalarNode || node.Decode(reflect.New(t).Interface()) != nil {
+			c.addWrongTypeProblem(node, t, path)
+		}
+	}
+}
+
+func (c *configChecker) checkMerge(node *yamlv3.Node, t reflect.Type, path string) {
+	if node.Kind == yamlv3.SequenceNode {
+		for _, item := range node.Content {
+			c.check(item, t, path)
+		}
+		return
+	}
+	c.check(node, t, path)
+}
+
+func (c *configChecker) checkRegexps(node *yamlv3.Node, path string) {
+	if node.Kind != yamlv3.SequenceNode {
+		return
+	}
+	for i, item := range node.Content {
+		if item.Kind != yamlv3.ScalarNode {
+			continue
+		}
+		if _, err := regexp.Compile(item.Value); err != nil {
+			c.addProblem(item, "invalid regular expression %q in %s[%d]: %v", item.Value, path, i, err)
+		}
+	}
+}
+
+func (c *configChecker) addWrongTypeProblem(node *yamlv3.Node, t reflect.Type, path string) {
+	if path == "" {
+		path = "configuration"
+	}
+	c.addProblem(node, "%s must be %s but is %s", path, typeDescription(t), nodeDescription(node))
+}
+
+type structField struct {
+	reflect.StructField
+	// yamlName is the key of the field in YAML
+	yamlName string
+	// owner is the name of the struct type that declares the field
+	owner string
+}
+
+// yamlFields returns the fields of the provided struct type that are decoded from YAML. The fields of inlined structs
+// are returned as fields of the provided type. Used both to check configurations and to generate their schema.
+func yamlFields(t reflect.Type) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+		tagParts := strings.Split(field.Tag.Get("yaml"), ",")
+		name := tagParts[0]
+		if name == "-" {
+			continue
+		}
+		inline := false
+		for _, opt := range tagParts[1:] {
+			if opt == "inline" {
+				inline = true
+			}
+		}
+		if inline {
+			fields = append(fields, yamlFields(field.Type)...)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields = append(fields, structField{
+			StructField: field,
+			yamlName:    name,
+			owner:       t.Name(),
+		})
+	}
+	return fields
+}
+
+func joinConfigPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func typeDescription(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeDescription(t.Elem())
+	case reflect.Struct, reflect.Map:
+		return "a mapping"
+	case reflect.Slice, reflect.Array:
+		return "a sequence"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	default:
+		return "a string"
+	}
+}
+
+func nodeDescription(node *yamlv3.Node) string {
+	switch node.Kind {
+	case yamlv3.MappingNode:
+		return "a mapping"
+	case yamlv3.SequenceNode:
+		return "a sequence"
+	default:
+		return fmt.Sprintf("%q", node.Value)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config_test
+
+import (
+	"testing"
+
+	"github.com/palantir/go-generate/gogenerate/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := config.LoadConfig("generate.yml", []byte(`
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      names:
+        - "^mock_.*\\.go$"
+    environment:
+      CGO_ENABLED: 0
+`))
+	require.NoError(t, err)
+
+	param := cfg.ToParam().Generators["foo"]
+	assert.Equal(t, "gen", param.GoGenDir)
+	assert.Equal(t, "0", param.Environment["CGO_ENABLED"])
+	assert.True(t, param.GenPaths.Match("gen/mock_foo.go"))
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	for currCaseNum, currCase := range []struct {
+		name      string
+		cfg       string
+		wantError string
+	}{
+		{
+			name: "unknown key",
+			cfg: `version: 1
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-path:
+      paths:
+        - "gen/output.txt"
+`,
+			wantError: `generate.yml:5:5: unknown key "gen-path" in generators.foo`,
+		},
+		{
+			name: "unknown top-level key",
+			cfg: `version: 1
+generator:
+  foo:
+    go-generate-dir: gen
+`,
+			wantError: `generate.yml:2:1: unknown key "generator"`,
+		},
+		{
+			name: "wrong types",
+			cfg: `version: 1
+generators:
+  foo:
+    go-generate-dir: [gen]
+    ignore-mode: sometimes
+    gen-paths:
+      paths: gen/output.txt
+`,
+			wantError: `generate.yml:4:22: generators.foo.go-generate-dir must be a string but is a sequence
+generate.yml:5:18: generators.foo.ignore-mode must be a boolean but is "sometimes"
+generate.yml:7:14: generators.foo.gen-paths.paths must be a sequence but is "gen/output.txt"`,
+		},
+		{
+			name: "invalid gen-paths regular expression",
+			cfg: `version: 1
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      names:
+        - "^valid$"
+        - "[a-"
+`,
+			wantError: "generate.yml:8:11: invalid regular expression \"[a-\" in generators.foo.gen-paths.names[1]: error parsing regexp: missing closing ]: `[a-`",
+		},
+		{
+			name: "v1 key in v0 configuration",
+			cfg: `generators:
+  foo:
+    go-generate-dir: gen
+    depends-on:
+      - bar
+`,
+			wantError: `generate.yml:4:5: unknown key "depends-on" in generators.foo`,
+		},
+		{
+			name: "invalid value",
+			cfg: `version: 1
+generators:
+  foo:
+    timeout: soon
+`,
+			wantError: `generate.yml: failed to unmarshal generate-plugin v1 configuration: invalid timeout "soon": time: invalid duration "soon"`,
+		},
+	} {
+		_, err := config.LoadConfig("generate.yml", []byte(currCase.cfg))
+		assert.EqualError(t, err, currCase.wantError, "Case %d: %s", currCaseNum, currCase.name)
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/palantir/go-generate/gogenerate"
+	v1 "github.com/palantir/go-generate/gogenerate/config/internal/v1"
+	"github.com/palantir/godel/v2/pkg/versionedconfig"
+)
+
+// ProposeConfig returns a configuration with one generator for every package directory that contains the provided
+// directives. Generators are named after the slash-separated path of their directory relative to the project directory
+// (the project directory itself is named "root"). If a directive invokes a recognized tool (stringer, mockgen or
+// protoc), the gen-paths of its generator are inferred from the arguments of the directive. The gen-paths of
+// directives that do not invoke a recognized tool are not inferred, so the returned configuration should be reviewed
+// before it is used.
+func ProposeConfig(directives []gogenerate.Directive) ProjectConfig {
+	genPaths := make(map[string]map[string]struct{})
+	for _, d := range directives {
+		dir := d.Dir()
+		if genPaths[dir] == nil {
+			genPaths[dir] = make(map[string]struct{})
+		}
+		for _, p := range inferGenPaths(d) {
+			genPaths[dir][p] = struct{}{}
+		}
+	}
+
+	cfg := ProjectConfig{
+		ConfigWithVersion: versionedconfig.ConfigWithVersion{
+			Version: "1",
+		},
+		Generators: make(map[string]v1.GeneratorConfig),
+	}
+	for dir, paths := range genPaths {
+		genCfg := v1.GeneratorConfig{
+			GoGenDir: dir,
+		}
+		for p := range paths {
+			genCfg.GenPaths.Paths = append(genCfg.GenPaths.Paths, p)
+		}
+		sort.Strings(genCfg.GenPaths.Paths)
+		cfg.Generators[proposedGeneratorName(dir)] = genCfg
+	}
+	return cfg
+}
+
+func proposedGeneratorName(dir string) string {
+	if dir == "." {
+		return "root"
+	}
+	return dir
+}
+
+// inferGenPaths returns the paths of the output of the provided directive relative to the project directory if the
+// directive invokes a recognized tool. Returns nil if the tool is not recognized or its output cannot be determined.
+func inferGenPaths(d gogenerate.Directive) []string {
+	args, ok := directiveArgs(d)
+	if !ok {
+		return nil
+	}
+	for i, arg := range args {
+		toolArgs := args[i+1:]
+		var outputs []string
+		switch toolName(arg) {
+		case "stringer":
+			if output, ok := flagValue(toolArgs, "output"); ok {
+				outputs = append(outputs, output)
+			} else if types, ok := flagValue(toolArgs, "type"); ok {
+				// stringer writes the output for "-type=T1,T2" to "t1_string.go" by default
+				outputs = append(outputs, strings.ToLower(strings.Split(types, ",")[0]+"_string.go"))
+			}
+		case "mockgen":
+			if output, ok := flagValue(toolArgs, "destination"); ok {
+				outputs = append(outputs, output)
+			}
+		case "protoc":
+			for _, flag := range []string{"go_out", "go-grpc_out"} {
+				if output, ok := flagValue(toolArgs, flag); ok {
+					outputs = append(outputs, protocOutput(output))
+				}
+			}
+		default:
+			continue
+		}
+
+		var genPaths []string
+		for _, output := range outputs {
+			if path.IsAbs(output) {
+				continue
+			}
+			if genPath := path.Join(d.Dir(), output); genPath != ".." && !strings.HasPrefix(genPath, "../") {
+				genPaths = append(genPaths, genPath)
+			}
+		}
+		return genPaths
+	}
+	return nil
+}
+
+// directiveArgs splits the command of the provided directive into arguments in the same manner as "go generate":
+// arguments are separated by spaces and double-quoted arguments are Go string literals. The "$GOFILE" and "$GOLINE"
+// variables are expanded. Returns false if the command cannot be split or contains other variables, since the values
+// of those variables are not known statically.
+func directiveArgs(d gogenerate.Directive) ([]string, bool) {
+	var args []string
+	line := d.Command
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			break
+		}
+		if line[0] == '"' {
+			end := 1
+			for ; end < len(line); end++ {
+				if line[end] == '\\' {
+					end++
+				} else if line[end] == '"' {
+					break
+				}
+			}
+			if end >= len(line) {
+				return nil, false
+			}
+			arg, err := strconv.Unquote(line[:end+1])
+			if err != nil {
+				return nil, false
+			}
+			args = append(args, arg)
+			line = line[end+1:]
+			continue
+		}
+		end := strings.IndexAny(line, " \t")
+		if end == -1 {
+			end = len(line)
+		}
+		args = append(args, line[:end])
+		line = line[end:]
+	}
+
+	replacer := strings.NewReplacer("$GOFILE", path.Base(d.File), "$GOLINE", strconv.Itoa(d.Line))
+	for i, arg := range args {
+		args[i] = replacer.Replace(arg)
+		if strings.Contains(args[i], "$") {
+			return nil, false
+		}
+	}
+	return args, true
+}
+
+// toolName returns the name of the tool invoked by the provided argument, which may be a path or a module path with a
+// version suffix such as "github.com/golang/mock/mockgen@v1.6.0".
+func toolName(arg string) string {
+	if idx := strings.Index(arg, "@"); idx != -1 {
+		arg = arg[:idx]
+	}
+	return path.Base(arg)
+}
+
+// flagValue returns the value of the flag with the provided name in the provided arguments. Flags may be specified
+// with one or two leading dashes and their values may be specified as part of the same argument ("-flag=value") or as
+// the following argument ("-flag value").
+func flagValue(args []string, name string) (string, bool) {
+	for i, arg := range args {
+		trimmed := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		if trimmed == arg {
+			continue
+		}
+		if value, ok := strings.CutPrefix(trimmed, name+"="); ok {
+			return value, true
+		}
+		if trimmed == name && i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
+
+// protocOutput returns the path relative to the directory of the directive of the output of the provided value of a
+// protoc "--*_out" flag. protoc writes the files for a proto file to a subdirectory of the output directory that is
+// determined by its Go import path or by its source path, so an output directory is matched as a whole. If the output
+// directory contains the directory of the directive, only the ".pb.go" files directly in it are matched so that the
+// sources of the package are not matched.
+func protocOutput(value string) string {
+	outDir := path.Clean(protocOutDir(value))
+	if outDir == "." || path.Base(outDir) == ".." {
+		return path.Join(outDir, "*.pb.go")
+	}
+	return outDir
+}
+
+// protocOutDir returns the output directory of the provided value of a protoc "--*_out" flag, which may be prefixed by
+// comma-separated parameters followed by a colon (for example, "paths=source_relative:gen").
+func protocOutDir(value string) string {
+	if idx := strings.LastIndex(value, ":"); idx != -1 {
+		return value[idx+1:]
+	}
+	return value
+}
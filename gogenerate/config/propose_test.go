@@ -0,0 +1,123 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config_test
+
+import (
+	"testing"
+
+	"github.com/palantir/go-generate/gogenerate"
+	"github.com/palantir/go-generate/gogenerate/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestProposeConfig(t *testing.T) {
+	for currCaseNum, currCase := range []struct {
+		name       string
+		directives []gogenerate.Directive
+		want       string
+	}{
+		{
+			name: "unrecognized tools do not have gen-paths",
+			directives: []gogenerate.Directive{
+				{File: "main.go", Line: 3, Command: "go run generator_main.go"},
+				{File: "foo/foo.go", Line: 3, Command: "echo foo"},
+			},
+			want: `version: "1"
+generators:
+  foo:
+    go-generate-dir: foo
+  root:
+    go-generate-dir: .
+`,
+		},
+		{
+			name: "stringer output is inferred",
+			directives: []gogenerate.Directive{
+				{File: "foo/foo.go", Line: 3, Command: "stringer -type=Color,Shape"},
+				{File: "foo/bar.go", Line: 3, Command: `go run golang.org/x/tools/cmd/stringer@v0.1.0 -type Size "-output=sizes_$GOFILE"`},
+			},
+			want: `version: "1"
+generators:
+  foo:
+    go-generate-dir: foo
+    gen-paths:
+      paths:
+      - foo/color_string.go
+      - foo/sizes_bar.go
+`,
+		},
+		{
+			name: "mockgen destination is inferred",
+			directives: []gogenerate.Directive{
+				{File: "pkg/foo/foo.go", Line: 3, Command: "mockgen -source=foo.go -destination ../mocks/foo.go"},
+			},
+			want: `version: "1"
+generators:
+  pkg/foo:
+    go-generate-dir: pkg/foo
+    gen-paths:
+      paths:
+      - pkg/mocks/foo.go
+`,
+		},
+		{
+			name: "protoc output directories are inferred",
+			directives: []gogenerate.Directive{
+				{File: "api/api.go", Line: 3, Command: "protoc --go_out=paths=source_relative:gen --go-grpc_out=. api.proto"},
+			},
+			want: `version: "1"
+generators:
+  api:
+    go-generate-dir: api
+    gen-paths:
+      paths:
+      - api/*.pb.go
+      - api/gen
+`,
+		},
+		{
+			name: "protoc output directory is inferred once for all plugins",
+			directives: []gogenerate.Directive{
+				{File: "api/api.go", Line: 3, Command: "protoc --go_out=gen --go-grpc_out=require_unimplemented_servers=false:gen/ api.proto"},
+			},
+			want: `version: "1"
+generators:
+  api:
+    go-generate-dir: api
+    gen-paths:
+      paths:
+      - api/gen
+`,
+		},
+		{
+			name: "outputs with unknown variables or outside of the project are not inferred",
+			directives: []gogenerate.Directive{
+				{File: "foo/foo.go", Line: 3, Command: "stringer -type=Color -output=$GOPACKAGE.go"},
+				{File: "foo/foo.go", Line: 4, Command: "mockgen -destination=../../mocks.go . Foo"},
+			},
+			want: `version: "1"
+generators:
+  foo:
+    go-generate-dir: foo
+`,
+		},
+	} {
+		got, err := yaml.Marshal(config.ProposeConfig(currCase.directives))
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		assert.Equal(t, currCase.want, string(got), "Case %d: %s", currCaseNum, currCase.name)
+	}
+}
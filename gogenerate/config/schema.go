@@ -0,0 +1,117 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// environmentSchema is the schema of environment variables. Values such as "CGO_ENABLED: 0" are converted to strings.
+var environmentSchema = map[string]interface{}{
+	"type":                 "object",
+	"additionalProperties": map[string]interface{}{"type": []string{"string", "number", "boolean"}},
+}
+
+// schemaOverrides specifies the schemas of the fields whose valid values cannot be determined from their Go types. Keys
+// are of the form "<type name>.<field name>".
+var schemaOverrides = map[string]map[string]interface{}{
+	// the version may be specified as a string or as a number
+	"ConfigWithVersion.Version":         {"enum": []interface{}{"1", 1}},
+	"ScanErrorsConfig.PermissionDenied": {"enum": []string{"fail", "warn", "skip"}},
+	"ScanErrorsConfig.NotExist":         {"enum": []string{"fail", "warn", "skip"}},
+	"OutputChecksConfig.Gofmt":          {"enum": []string{"check", "fix"}},
+	"GeneratorConfig.Command":           {"type": "array", "items": map[string]interface{}{"type": "string"}, "minItems": 1},
+	"GeneratorConfig.Environment":       environmentSchema,
+	"DefaultsConfig.Environment":        environmentSchema,
+}
+
+// Schema returns the JSON Schema of the current version of the configuration, which is generated from the Go types of
+// the configuration. Every struct type other than the root ProjectConfig is a definition, and objects do not allow
+// properties that are not fields of their types so that misspelled keys are reported. The schema requires "version"
+// because a configuration that does not specify its version is read as a previous version.
+func Schema() ([]byte, error) {
+	s := &schemaGenerator{
+		defs: make(map[string]interface{}),
+	}
+	root := s.structSchema(reflect.TypeOf(ProjectConfig{}))
+	root["required"] = []string{"version"}
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["title"] = "go-generate configuration"
+	root["$defs"] = s.defs
+	schemaBytes, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal JSON Schema")
+	}
+	return append(schemaBytes, '\n'), nil
+}
+
+type schemaGenerator struct {
+	// defs maps the names of struct types to their schemas
+	defs map[string]interface{}
+}
+
+func (s *schemaGenerator) typeSchema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return s.typeSchema(t.Elem())
+	case reflect.Struct:
+		if _, ok := s.defs[t.Name()]; !ok {
+			// reserve the name before generating the schema so that recursive types terminate
+			s.defs[t.Name()] = nil
+			s.defs[t.Name()] = s.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": s.typeSchema(t.Elem()),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": s.typeSchema(t.Elem()),
+		}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
+
+// structSchema returns the schema of the provided struct type. Fields are named by their "yaml" tags, and the fields of
+// inlined structs are properties of the returned schema.
+func (s *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	for _, field := range yamlFields(t) {
+		if override, ok := schemaOverrides[field.owner+"."+field.Name]; ok {
+			properties[field.yamlName] = override
+			continue
+		}
+		properties[field.yamlName] = s.typeSchema(field.Type)
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
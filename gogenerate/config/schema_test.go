@@ -0,0 +1,92 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config_test
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/palantir/go-generate/gogenerate/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaInSync(t *testing.T) {
+	got, err := config.Schema()
+	require.NoError(t, err)
+
+	checkedIn, err := os.ReadFile("generate.schema.json")
+	require.NoError(t, err)
+	assert.Equal(t, string(checkedIn), string(got), "generate.schema.json is out of date: run `go run . config schema > gogenerate/config/generate.schema.json` from the root of the repository")
+}
+
+func TestSchemaProperties(t *testing.T) {
+	schemaBytes, err := config.Schema()
+	require.NoError(t, err)
+
+	var schema struct {
+		Properties           map[string]json.RawMessage `json:"properties"`
+		AdditionalProperties bool                       `json:"additionalProperties"`
+		Required             []string                   `json:"required"`
+		Defs                 map[string]struct {
+			Properties           map[string]json.RawMessage `json:"properties"`
+			AdditionalProperties bool                       `json:"additionalProperties"`
+		} `json:"$defs"`
+	}
+	err = json.Unmarshal(schemaBytes, &schema)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"coverage-ignore", "defaults", "discover-fragments", "exclude", "generators", "scan-errors", "version"}, sortedKeys(schema.Properties))
+	assert.False(t, schema.AdditionalProperties)
+	assert.Equal(t, []string{"version"}, schema.Required)
+	assert.Equal(t, []string{"names", "paths"}, sortedKeys(schema.Defs["NamesPathsCfg"].Properties))
+	assert.False(t, schema.Defs["NamesPathsCfg"].AdditionalProperties)
+	assert.Equal(t, []string{
+		"command",
+		"depends-on",
+		"environment",
+		"exclude",
+		"flags",
+		"gen-paths",
+		"go-generate-dir",
+		"ignore-mode",
+		"inputs",
+		"normalize",
+		"output-checks",
+		"tags",
+		"timeout",
+	}, sortedKeys(schema.Defs["GeneratorConfig"].Properties))
+	assert.False(t, schema.Defs["GeneratorConfig"].AdditionalProperties)
+	assert.Equal(t, []string{
+		"environment",
+		"exclude",
+		"flags",
+		"ignore-mode",
+		"normalize",
+		"output-checks",
+		"timeout",
+	}, sortedKeys(schema.Defs["DefaultsConfig"].Properties))
+}
+
+func sortedKeys(m map[string]json.RawMessage) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
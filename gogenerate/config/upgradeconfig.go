@@ -17,6 +17,7 @@ package config
 import (
 	"github.com/palantir/go-generate/gogenerate/config/internal/legacy"
 	v0 "github.com/palantir/go-generate/gogenerate/config/internal/v0"
+	v1 "github.com/palantir/go-generate/gogenerate/config/internal/v1"
 	"github.com/palantir/godel/v2/pkg/versionedconfig"
 	"github.com/pkg/errors"
 )
@@ -35,7 +36,13 @@ func UpgradeConfig(cfgBytes []byte) ([]byte, error) {
 	}
 	switch version {
 	case "", "0":
-		return v0.UpgradeConfig(cfgBytes)
+		v0Bytes, err := v0.UpgradeConfig(cfgBytes)
+		if err != nil {
+			return nil, err
+		}
+		return v1.UpgradeV0Config(v0Bytes)
+	case "1":
+		return v1.UpgradeConfig(cfgBytes)
 	default:
 		return nil, errors.Errorf("unsupported version: %s", version)
 	}
@@ -0,0 +1,193 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config_test
+
+import (
+	"testing"
+
+	"github.com/palantir/go-generate/gogenerate/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestUpgradeConfig(t *testing.T) {
+	for currCaseNum, currCase := range []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "legacy configuration is upgraded to v1",
+			in: `legacy-config: true
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "gen/output.txt"
+    environment:
+      GOOS: darwin
+`,
+			want: `version: "1"
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+      - gen/output.txt
+    environment:
+      GOOS: darwin
+`,
+		},
+		{
+			name: "v0 configuration is upgraded to v1",
+			in: `generators:
+  mocks:
+    go-generate-dir: mocks
+    gen-paths:
+      names:
+        - ".+_mock\\.go"
+    environment:
+      GOFLAGS: -mod=vendor
+  proto:
+    go-generate-dir: proto
+    gen-paths:
+      paths:
+        - "proto/generated"
+`,
+			want: `version: "1"
+generators:
+  mocks:
+    go-generate-dir: mocks
+    gen-paths:
+      names:
+      - .+_mock\.go
+    environment:
+      GOFLAGS: -mod=vendor
+  proto:
+    go-generate-dir: proto
+    gen-paths:
+      paths:
+      - proto/generated
+`,
+		},
+		{
+			name: "empty v0 configuration is upgraded to v1",
+			in:   ``,
+			want: `version: "1"
+`,
+		},
+		{
+			name: "v1 configuration is unchanged",
+			in: `version: 1
+generators:
+  proto:
+    go-generate-dir: proto
+    command: [buf, generate]
+    flags: ["--template", "buf.gen.yaml"]
+    gen-paths:
+      paths:
+        - "proto/generated"
+    timeout: 5m
+    tags: [proto]
+    output-checks:
+      gofmt: check
+`,
+			want: `version: 1
+generators:
+  proto:
+    go-generate-dir: proto
+    command: [buf, generate]
+    flags: ["--template", "buf.gen.yaml"]
+    gen-paths:
+      paths:
+        - "proto/generated"
+    timeout: 5m
+    tags: [proto]
+    output-checks:
+      gofmt: check
+`,
+		},
+		{
+			name: "v1 configuration with defaults is unchanged",
+			in: `version: 1
+defaults:
+  environment:
+    GOFLAGS: -mod=mod
+  exclude:
+    names:
+      - "doc.go"
+generators:
+  proto:
+    go-generate-dir: proto
+    gen-paths:
+      paths:
+        - "proto/generated"
+    exclude:
+      paths:
+        - "proto/generated/static"
+`,
+			want: `version: 1
+defaults:
+  environment:
+    GOFLAGS: -mod=mod
+  exclude:
+    names:
+      - "doc.go"
+generators:
+  proto:
+    go-generate-dir: proto
+    gen-paths:
+      paths:
+        - "proto/generated"
+    exclude:
+      paths:
+        - "proto/generated/static"
+`,
+		},
+	} {
+		got, err := config.UpgradeConfig([]byte(currCase.in))
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		assert.Equal(t, currCase.want, string(got), "Case %d: %s", currCaseNum, currCase.name)
+
+		// the upgraded configuration is a valid v1 configuration that is not changed by upgrading it again
+		upgradedAgain, err := config.UpgradeConfig(got)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		assert.Equal(t, string(got), string(upgradedAgain), "Case %d: %s", currCaseNum, currCase.name)
+
+		var cfg config.ProjectConfig
+		err = yaml.UnmarshalStrict(got, &cfg)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		assert.Equal(t, "1", cfg.Version, "Case %d: %s", currCaseNum, currCase.name)
+	}
+}
+
+func TestUpgradeConfigUnsupportedVersion(t *testing.T) {
+	_, err := config.UpgradeConfig([]byte(`version: 2
+`))
+	assert.EqualError(t, err, "unsupported version: 2")
+}
+
+func TestUpgradeV0ConfigWithV1Fields(t *testing.T) {
+	// fields that were introduced in v1 must be specified in a v1 configuration
+	_, err := config.UpgradeConfig([]byte(`generators:
+  foo:
+    go-generate-dir: gen
+    depends-on:
+      - bar
+`))
+	assert.EqualError(t, err, "failed to unmarshal generate-plugin v0 configuration: yaml: unmarshal errors:\n  line 4: field depends-on not found in type v0.GeneratorConfig")
+}
@@ -0,0 +1,145 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate_test
+
+import (
+	"testing"
+
+	"github.com/palantir/go-generate/gogenerate"
+	"github.com/palantir/pkg/matcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutionOrder(t *testing.T) {
+	for currCaseNum, currCase := range []struct {
+		name       string
+		generators gogenerate.Generators
+		want       []string
+		wantErr    string
+	}{
+		{
+			name: "generators without dependencies are sorted",
+			generators: gogenerate.Generators{
+				"c": {},
+				"a": {},
+				"b": {},
+			},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "dependencies are run first",
+			generators: gogenerate.Generators{
+				"mocks": {DependsOn: []string{"proto"}},
+				"proto": {},
+				"zeta":  {},
+				"alpha": {DependsOn: []string{"zeta"}},
+			},
+			want: []string{"proto", "mocks", "zeta", "alpha"},
+		},
+		{
+			name: "unknown dependency",
+			generators: gogenerate.Generators{
+				"mocks": {DependsOn: []string{"proto"}},
+			},
+			wantErr: `generator "mocks" depends on generator "proto", which does not exist`,
+		},
+		{
+			name: "cycle",
+			generators: gogenerate.Generators{
+				"a": {DependsOn: []string{"b"}},
+				"b": {DependsOn: []string{"a"}},
+				"c": {DependsOn: []string{"a"}},
+				"d": {},
+			},
+			wantErr: `generators [a b c] cannot be ordered because their dependencies contain a cycle`,
+		},
+	} {
+		got, err := currCase.generators.ExecutionOrder()
+		if currCase.wantErr != "" {
+			assert.EqualError(t, err, currCase.wantErr, "Case %d: %s", currCaseNum, currCase.name)
+			continue
+		}
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		assert.Equal(t, currCase.want, got, "Case %d: %s", currCaseNum, currCase.name)
+	}
+}
+
+func TestAffected(t *testing.T) {
+	generators := gogenerate.Generators{
+		"proto": {
+			GoGenDir: "proto",
+			Inputs:   matcher.Name(`.+\.proto`),
+		},
+		"mocks": {
+			GoGenDir:  "./mocks",
+			DependsOn: []string{"proto"},
+		},
+		"server": {
+			GoGenDir:  "server",
+			DependsOn: []string{"mocks"},
+		},
+		"other": {
+			GoGenDir: "other",
+			GenPaths: matcher.Path("other/generated"),
+		},
+	}
+	for currCaseNum, currCase := range []struct {
+		name         string
+		changedPaths []string
+		want         []string
+	}{
+		{
+			name:         "change in generator directory affects generator",
+			changedPaths: []string{"other/other.go"},
+			want:         []string{"other"},
+		},
+		{
+			name:         "change in subdirectory of generator directory does not affect generator",
+			changedPaths: []string{"other/sub/sub.go"},
+		},
+		{
+			name:         "change to output affects generator",
+			changedPaths: []string{"other/generated/output.go"},
+			want:         []string{"other"},
+		},
+		{
+			name:         "change to input affects generator and its dependents",
+			changedPaths: []string{"api/service.proto"},
+			want:         []string{"mocks", "proto", "server"},
+		},
+		{
+			name:         "change to dependent affects only dependents",
+			changedPaths: []string{"mocks/mocks.go"},
+			want:         []string{"mocks", "server"},
+		},
+	} {
+		assert.Equal(t, currCase.want, generators.Affected(currCase.changedPaths), "Case %d: %s", currCaseNum, currCase.name)
+	}
+}
+
+func TestSubset(t *testing.T) {
+	generators := gogenerate.Generators{
+		"proto":  {},
+		"mocks":  {DependsOn: []string{"proto"}},
+		"server": {DependsOn: []string{"mocks", "proto"}},
+	}
+	subset := generators.Subset([]string{"mocks", "server"})
+	order, err := subset.ExecutionOrder()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mocks", "server"}, order)
+	assert.Equal(t, []string{"mocks"}, subset["server"].DependsOn)
+	assert.Equal(t, []string{"mocks", "proto"}, generators["server"].DependsOn)
+}
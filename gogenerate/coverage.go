@@ -0,0 +1,64 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// UncoveredDirectives returns the "//go:generate" directives in the project that are not run by any generator: that
+// is, the directives in directories that are not the "go-generate-dir" of any generator. Directories and files matched
+// by the CoverageIgnore matcher of the provided parameters are skipped.
+func UncoveredDirectives(rootDir string, projectParam ProjectParam) ([]Directive, error) {
+	directives, err := FindDirectives(rootDir, projectParam.CoverageIgnore)
+	if err != nil {
+		return nil, err
+	}
+	covered := make(map[string]struct{})
+	for _, v := range projectParam.Generators {
+		covered[path.Clean(filepath.ToSlash(v.GoGenDir))] = struct{}{}
+	}
+	var uncovered []Directive
+	for _, d := range directives {
+		if _, ok := covered[d.Dir()]; !ok {
+			uncovered = append(uncovered, d)
+		}
+	}
+	return uncovered, nil
+}
+
+// CheckCoverage returns true if every "//go:generate" directive in the project is run by a generator, false otherwise.
+// If the check is not successful, the file, line and command of every directive that is not run by any generator are
+// written as output to the provided writer. Returns an error if an error is encountered when running the check itself.
+func CheckCoverage(rootDir string, projectParam ProjectParam, stdout io.Writer) (bool, error) {
+	uncovered, err := UncoveredDirectives(rootDir, projectParam)
+	if err != nil {
+		return false, err
+	}
+	if len(uncovered) == 0 {
+		return true, nil
+	}
+
+	outputParts := []string{"go:generate directives that are not run by any generator:"}
+	for _, d := range uncovered {
+		outputParts = append(outputParts, fmt.Sprintf("  %s:%d: %s", d.File, d.Line, d.Command))
+	}
+	_, _ = fmt.Fprintln(stdout, strings.Join(outputParts, "\n"))
+	return false, nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/nmiyake/pkg/gofiles"
+	"github.com/palantir/go-generate/gogenerate"
+	"github.com/palantir/pkg/matcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCoverage(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "gen/gen.go",
+			Src: `package gen
+
+//go:generate go run generator_main.go
+`,
+		},
+		{
+			RelPath: "gen/sub/sub.go",
+			Src: `package sub
+
+//go:generate stringer -type=Color
+`,
+		},
+		{
+			RelPath: "other/other.go",
+			Src: `package other
+
+//go:generate mockgen -destination=mocks.go . Foo
+`,
+		},
+		{
+			RelPath: "ignored/ignored.go",
+			Src: `package ignored
+
+//go:generate echo ignored
+`,
+		},
+	})
+	require.NoError(t, err)
+
+	for currCaseNum, currCase := range []struct {
+		name         string
+		projectParam gogenerate.ProjectParam
+		want         bool
+		wantOutput   string
+	}{
+		{
+			name: "directives outside of generator directories are reported",
+			projectParam: gogenerate.ProjectParam{
+				Generators: gogenerate.Generators{
+					"gen": gogenerate.GeneratorParam{
+						GoGenDir: "./gen",
+					},
+				},
+			},
+			wantOutput: `go:generate directives that are not run by any generator:
+  gen/sub/sub.go:3: stringer -type=Color
+  ignored/ignored.go:3: echo ignored
+  other/other.go:3: mockgen -destination=mocks.go . Foo
+`,
+		},
+		{
+			name: "ignored directives are not reported",
+			projectParam: gogenerate.ProjectParam{
+				Generators: gogenerate.Generators{
+					"gen": gogenerate.GeneratorParam{
+						GoGenDir: "gen",
+					},
+					"sub": gogenerate.GeneratorParam{
+						GoGenDir: "gen/sub",
+					},
+				},
+				CoverageIgnore: matcher.Any(matcher.Path("ignored"), matcher.Name(`^other\.go$`)),
+			},
+			want: true,
+		},
+	} {
+		buf := &bytes.Buffer{}
+		got, err := gogenerate.CheckCoverage(tmpDir, currCase.projectParam, buf)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		assert.Equal(t, currCase.want, got, "Case %d: %s", currCaseNum, currCase.name)
+		assert.Equal(t, currCase.wantOutput, buf.String(), "Case %d: %s", currCaseNum, currCase.name)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// number of unchanged lines shown before and after every change in a unified diff
+const diffContextLines = 3
+
+type diffOp struct {
+	kind byte // one of ' ', '-' or '+'
+	line string
+}
+
+// unifiedDiff returns a unified diff of the provided content. Returns an empty string if the content is equal.
+func unifiedDiff(aName, bName string, a, b []byte) string {
+	if bytes.Equal(a, b) {
+		return ""
+	}
+	if bytes.IndexByte(a, 0) != -1 || bytes.IndexByte(b, 0) != -1 {
+		return fmt.Sprintf("Binary files %s and %s differ", aName, bName)
+	}
+
+	ops := diffLines(splitLines(a), splitLines(b))
+	parts := []string{
+		fmt.Sprintf("--- %s", aName),
+		fmt.Sprintf("+++ %s", bName),
+	}
+	parts = append(parts, diffHunks(ops)...)
+	return strings.Join(parts, "\n")
+}
+
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.Split(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the edit script that transforms a into b using Myers' algorithm.
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	maxD := n + m
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+
+	// trace[d] stores the furthest reaching x for every diagonal k before iteration d
+	var trace [][]int
+search:
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	var reversed []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			reversed = append(reversed, diffOp{kind: ' ', line: a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, diffOp{kind: '+', line: b[y-1]})
+			} else {
+				reversed = append(reversed, diffOp{kind: '-', line: a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	ops := make([]diffOp, len(reversed))
+	for i, op := range reversed {
+		ops[len(reversed)-1-i] = op
+	}
+	return ops
+}
+
+// diffHunks groups the provided edit script into unified diff hunks.
+func diffHunks(ops []diffOp) []string {
+	var hunks []string
+	aLine, bLine := 1, 1
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			aLine++
+			bLine++
+			i++
+			continue
+		}
+
+		// start hunk with up to diffContextLines of leading context
+		start := max(i-diffContextLines, 0)
+		for j := start; j < i; j++ {
+			aLine--
+			bLine--
+		}
+		aStart, bStart := aLine, bLine
+		aCount, bCount := 0, 0
+		var lines []string
+
+		end := start
+		for end < len(ops) {
+			if ops[end].kind == ' ' {
+				// end hunk if the run of unchanged lines is long enough to separate it from the next change
+				run := 0
+				for end+run < len(ops) && ops[end+run].kind == ' ' {
+					run++
+				}
+				if end+run == len(ops) || run > 2*diffContextLines {
+					run = min(run, diffContextLines)
+					for j := 0; j < run; j++ {
+						lines = append(lines, " "+ops[end+j].line)
+					}
+					aCount += run
+					bCount += run
+					end += run
+					break
+				}
+			}
+			op := ops[end]
+			lines = append(lines, string(op.kind)+op.line)
+			switch op.kind {
+			case ' ':
+				aCount++
+				bCount++
+			case '-':
+				aCount++
+			case '+':
+				bCount++
+			}
+			end++
+		}
+		hunks = append(hunks, fmt.Sprintf("@@ -%s +%s @@", hunkRange(aStart, aCount), hunkRange(bStart, bCount)))
+		hunks = append(hunks, lines...)
+
+		aLine = aStart + aCount
+		bLine = bStart + bCount
+		i = end
+	}
+	return hunks
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		// by convention, an empty range refers to the line before the hunk
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,175 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate
+
+import (
+	"bufio"
+	"go/build"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/palantir/pkg/matcher"
+	"github.com/pkg/errors"
+)
+
+// Directive is a "//go:generate" directive in a Go source file.
+type Directive struct {
+	// File is the slash-separated path of the file that contains the directive relative to the project directory.
+	File string `json:"file"`
+	// Line is the 1-based line number of the directive.
+	Line int `json:"line"`
+	// Command is the command specified by the directive.
+	Command string `json:"command"`
+}
+
+// Dir returns the slash-separated path of the directory that contains the directive relative to the project directory.
+func (d Directive) Dir() string {
+	return path.Dir(d.File)
+}
+
+// FindDirectives returns all of the "//go:generate" directives in the Go files in the project. Directories that are
+// ignored by the go tool ("vendor", "testdata" and directories whose names begin with "." or "_") and directories and
+// files matched by the provided exclude matcher (which may be nil) are skipped. The returned directives are sorted by
+// file and line.
+func FindDirectives(rootDir string, exclude matcher.Matcher) ([]Directive, error) {
+	var directives []Directive
+	if err := walkGoFiles(rootDir, exclude, func(relFile string) error {
+		fileDirectives, err := fileDirectives(filepath.Join(rootDir, "."), relFile)
+		if err != nil {
+			return err
+		}
+		directives = append(directives, fileDirectives...)
+		return nil
+	}); err != nil {
+		return nil, errors.Wrapf(err, "failed to find go:generate directives")
+	}
+	sortDirectives(directives)
+	return directives, nil
+}
+
+// walkGoFiles calls the provided function with the slash-separated path relative to the project directory of every Go
+// file in the project. Directories that are ignored by the go tool and directories and files matched by the provided
+// exclude matcher (which may be nil) are skipped.
+func walkGoFiles(rootDir string, exclude matcher.Matcher, fn func(relFile string) error) error {
+	root := filepath.Join(rootDir, ".")
+	return filepath.WalkDir(root, func(currPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(root, currPath)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if relPath != "." && (ignoredByGoTool(d.Name()) || (exclude != nil && exclude.Match(relPath))) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(relPath, ".go") || (exclude != nil && exclude.Match(relPath)) {
+			return nil
+		}
+		return fn(filepath.ToSlash(relPath))
+	})
+}
+
+// DirDirectives returns the "//go:generate" directives in the Go files in the provided directory (but not in its
+// subdirectories), which are the directives that are run by running "go generate" in the directory. The returned
+// directives are sorted by file and line.
+func DirDirectives(rootDir, relDir string) ([]Directive, error) {
+	root := filepath.Join(rootDir, ".")
+	entries, err := os.ReadDir(filepath.Join(root, relDir))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read directory %q", relDir)
+	}
+	var directives []Directive
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		fileDirectives, err := fileDirectives(root, path.Join(filepath.ToSlash(relDir), entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, fileDirectives...)
+	}
+	sortDirectives(directives)
+	return directives, nil
+}
+
+// sortDirectives sorts the provided directives by file. The directives of every file must be in line order.
+func sortDirectives(directives []Directive) {
+	sort.SliceStable(directives, func(i, j int) bool {
+		return directives[i].File < directives[j].File
+	})
+}
+
+func ignoredByGoTool(dirName string) bool {
+	return dirName == "vendor" || dirName == "testdata" || strings.HasPrefix(dirName, ".") || strings.HasPrefix(dirName, "_")
+}
+
+// fileDirectives returns the directives in the provided file if it is a Go file that satisfies the build constraints
+// of the default build context. Like "go generate", the file is scanned line by line without being parsed.
+func fileDirectives(rootDir, relFile string) ([]Directive, error) {
+	if !strings.HasSuffix(relFile, ".go") {
+		return nil, nil
+	}
+	absFile := filepath.Join(rootDir, filepath.FromSlash(relFile))
+	if match, err := build.Default.MatchFile(filepath.Dir(absFile), filepath.Base(absFile)); err != nil {
+		return nil, errors.Wrapf(err, "failed to evaluate build constraints of %q", relFile)
+	} else if !match {
+		return nil, nil
+	}
+
+	f, err := os.Open(absFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %q", relFile)
+	}
+	defer func() {
+		// file is opened for reading only, so safe to ignore errors on close
+		_ = f.Close()
+	}()
+
+	var directives []Directive
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		if command, ok := directiveCommand(scanner.Text()); ok {
+			directives = append(directives, Directive{
+				File:    relFile,
+				Line:    lineNum,
+				Command: command,
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed to read %q", relFile)
+	}
+	return directives, nil
+}
+
+// directiveCommand returns the command of the provided line if it is a "//go:generate" directive.
+func directiveCommand(line string) (string, bool) {
+	const prefix = "//go:generate"
+	rest, ok := strings.CutPrefix(strings.TrimSuffix(line, "\r"), prefix)
+	if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+		return "", false
+	}
+	return strings.TrimSpace(rest), true
+}
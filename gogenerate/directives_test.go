@@ -0,0 +1,111 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate_test
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/nmiyake/pkg/gofiles"
+	"github.com/palantir/go-generate/gogenerate"
+	"github.com/palantir/pkg/matcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindDirectives(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "main.go",
+			Src: `package main
+
+//go:generate go run generator_main.go
+`,
+		},
+		{
+			RelPath: "foo/foo.go",
+			Src: `package foo
+
+//go:generate stringer -type=Color
+//go:generatenotadirective
+// go:generate not a directive
+//go:generate	mockgen -destination=mocks.go . Foo
+`,
+		},
+		{
+			RelPath: "foo/foo_test.go",
+			Src: `package foo_test
+
+//go:generate echo test
+`,
+		},
+		{
+			RelPath: "foo/ignored.go",
+			Src: `//go:build ignore
+
+package foo
+
+//go:generate echo ignored
+`,
+		},
+		{
+			RelPath: "vendor/bar/bar.go",
+			Src: `package bar
+
+//go:generate echo vendor
+`,
+		},
+		{
+			RelPath: "testdata/bar.go",
+			Src: `package bar
+
+//go:generate echo testdata
+`,
+		},
+		{
+			RelPath: "excluded/excluded.go",
+			Src: `package excluded
+
+//go:generate echo excluded
+`,
+		},
+	})
+	require.NoError(t, err)
+	err = os.WriteFile(path.Join(tmpDir, "foo", "notes.txt"), []byte("//go:generate echo text\n"), 0644)
+	require.NoError(t, err)
+
+	got, err := gogenerate.FindDirectives(tmpDir, matcher.Path("excluded"))
+	require.NoError(t, err)
+	assert.Equal(t, []gogenerate.Directive{
+		{File: "foo/foo.go", Line: 3, Command: "stringer -type=Color"},
+		{File: "foo/foo.go", Line: 6, Command: "mockgen -destination=mocks.go . Foo"},
+		{File: "foo/foo_test.go", Line: 3, Command: "echo test"},
+		{File: "main.go", Line: 3, Command: "go run generator_main.go"},
+	}, got)
+
+	got, err = gogenerate.DirDirectives(tmpDir, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, []gogenerate.Directive{
+		{File: "foo/foo.go", Line: 3, Command: "stringer -type=Color"},
+		{File: "foo/foo.go", Line: 6, Command: "mockgen -destination=mocks.go . Foo"},
+		{File: "foo/foo_test.go", Line: 3, Command: "echo test"},
+	}, got)
+}
@@ -0,0 +1,138 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DryRun writes what Run would do for the provided parameters to the provided writer without running any generators or
+// modifying any files. For every generator (in execution order), the command, working directory and environment
+// variables specified by the generator are written along with the commands that "go generate -n" reports would be run
+// (unless the generator specifies its own command or its directory does not contain any go:generate directives) and the
+// paths that are currently matched by its GenPaths. Because environment variables set in the environment of the current
+// process take precedence over the ones specified by a generator, every variable is written with the source of its
+// effective value.
+func DryRun(rootDir string, projectParam ProjectParam, stdout io.Writer) error {
+	order, err := projectParam.Generators.ExecutionOrder()
+	if err != nil {
+		return err
+	}
+	matched, err := matchedPaths(rootDir, projectParam)
+	if err != nil {
+		return err
+	}
+
+	hostEnv := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			hostEnv[k] = v
+		}
+	}
+
+	outputParts := []string{fmt.Sprintf("Dry run: would run %d generators in order %v", len(order), order)}
+	for _, k := range order {
+		v := projectParam.Generators[k]
+		cmd := generatorCmd(context.Background(), rootDir, v)
+		directiveLines := []string{"(not applicable: the generator specifies its own command)"}
+		if len(v.Command) == 0 {
+			if directiveLines, err = dryRunDirectives(rootDir, v); err != nil {
+				return err
+			}
+		}
+
+		var envLines []string
+		for _, envKey := range sortedEnvKeys(v.Environment) {
+			cfgVal := v.Environment[envKey]
+			if hostVal, ok := hostEnv[envKey]; ok && hostVal != cfgVal {
+				envLines = append(envLines, fmt.Sprintf("%s=%s (host value overrides configured value %q)", envKey, hostVal, cfgVal))
+			} else {
+				envLines = append(envLines, fmt.Sprintf("%s=%s (configured)", envKey, cfgVal))
+			}
+		}
+		inherited := 0
+		for hostKey := range hostEnv {
+			if _, ok := v.Environment[hostKey]; !ok {
+				inherited++
+			}
+		}
+		envLines = append(envLines, fmt.Sprintf("%d other variables inherited from the host", inherited))
+
+		outputParts = append(outputParts,
+			"",
+			fmt.Sprintf("%s:", k),
+			fmt.Sprintf("  command: %s", strings.Join(cmd.Args, " ")),
+			fmt.Sprintf("  dir: %s", cmd.Dir),
+			"  environment:",
+		)
+		outputParts = append(outputParts, indentLines(envLines)...)
+		outputParts = append(outputParts, "  directives (go generate -n):")
+		outputParts = append(outputParts, indentLines(directiveLines)...)
+		outputParts = append(outputParts, "  matched paths:")
+		outputParts = append(outputParts, indentLines(matched[k])...)
+	}
+	_, _ = fmt.Fprintln(stdout, strings.Join(outputParts, "\n"))
+	return nil
+}
+
+// dryRunDirectives returns the commands that "go generate -n" reports would be run for the provided generator. "go
+// generate" is not run if the directory of the generator does not contain any go:generate directives, since it fails if
+// the directory does not contain any Go files.
+func dryRunDirectives(rootDir string, param GeneratorParam) ([]string, error) {
+	dirDirectives, err := DirDirectives(rootDir, param.GoGenDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(dirDirectives) == 0 {
+		return nil, nil
+	}
+	cmd := generatorCmd(context.Background(), rootDir, param, "-n")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to run %s in %q: %s", strings.Join(cmd.Args, " "), cmd.Dir, strings.TrimSpace(string(output)))
+	}
+	return strings.Split(strings.TrimSpace(string(output)), "\n"), nil
+}
+
+func sortedEnvKeys(env map[string]string) []string {
+	var keys []string
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// indentLines returns the provided lines indented for output by DryRun. Empty lines are omitted and "(none)" is
+// returned if there are no lines.
+func indentLines(lines []string) []string {
+	var indented []string
+	for _, line := range lines {
+		if line != "" {
+			indented = append(indented, "    "+line)
+		}
+	}
+	if len(indented) == 0 {
+		return []string{"    (none)"}
+	}
+	return indented
+}
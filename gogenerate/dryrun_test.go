@@ -0,0 +1,129 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/nmiyake/pkg/gofiles"
+	"github.com/palantir/go-generate/gogenerate"
+	"github.com/palantir/pkg/matcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRun(t *testing.T) {
+	testDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	// generator_main.go does not exist, so running the directives would fail
+	_, err = gofiles.Write(testDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "gen/gen.go",
+			Src: `package gen
+
+//go:generate go run generator_main.go
+//go:generate echo $GOFILE $GOLINE
+`,
+		},
+		{
+			RelPath: "gen/output.txt",
+			Src:     `output`,
+		},
+		{
+			RelPath: "other/other.go",
+			Src:     `package other`,
+		},
+		{
+			RelPath: "proto/foo.proto",
+			Src:     `syntax = "proto3";`,
+		},
+	})
+	require.NoError(t, err)
+
+	t.Setenv("GOGEN_DRY_RUN_HOST", "host-val")
+	projectParam := gogenerate.ProjectParam{
+		Generators: gogenerate.Generators{
+			"gen": gogenerate.GeneratorParam{
+				GoGenDir: "gen",
+				// flags may end with package patterns, after which "-n" would not be recognized as a flag
+				Flags:    []string{"./..."},
+				GenPaths: matcher.Path("gen/output.txt"),
+				Environment: map[string]string{
+					"GOGEN_DRY_RUN_CONFIG": "config-val",
+					"GOGEN_DRY_RUN_HOST":   "config-val",
+				},
+			},
+			"other": gogenerate.GeneratorParam{
+				GoGenDir:  "other",
+				GenPaths:  matcher.Path("other/output.txt"),
+				DependsOn: []string{"gen"},
+			},
+			// the directory does not contain any Go files, so "go generate" would fail
+			"proto": gogenerate.GeneratorParam{
+				GoGenDir:  "proto",
+				GenPaths:  matcher.Path("proto/generated"),
+				DependsOn: []string{"other"},
+			},
+		},
+	}
+	buf := &bytes.Buffer{}
+	err = gogenerate.DryRun(testDir, projectParam, buf)
+	require.NoError(t, err)
+
+	hostEnvCount := len(os.Environ())
+	want := fmt.Sprintf(`Dry run: would run 3 generators in order [gen other proto]
+
+gen:
+  command: go generate ./...
+  dir: %s
+  environment:
+    GOGEN_DRY_RUN_CONFIG=config-val (configured)
+    GOGEN_DRY_RUN_HOST=host-val (host value overrides configured value "config-val")
+    %d other variables inherited from the host
+  directives (go generate -n):
+    go run generator_main.go
+    echo gen.go 4
+  matched paths:
+    gen/output.txt
+
+other:
+  command: go generate
+  dir: %s
+  environment:
+    %d other variables inherited from the host
+  directives (go generate -n):
+    (none)
+  matched paths:
+    (none)
+
+proto:
+  command: go generate
+  dir: %s
+  environment:
+    %d other variables inherited from the host
+  directives (go generate -n):
+    (none)
+  matched paths:
+    (none)
+`, path.Join(testDir, "gen"), hostEnvCount-1, path.Join(testDir, "other"), hostEnvCount, path.Join(testDir, "proto"), hostEnvCount)
+	assert.Equal(t, want, buf.String())
+}
@@ -15,24 +15,24 @@
 package gogenerate
 
 import (
-	"crypto/sha256"
+	"context"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"os"
 	"os/exec"
 	"path"
-	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
-	"github.com/palantir/pkg/matcher"
 	"github.com/pkg/errors"
 )
 
 // Run runs the generate task specified by the provided parameters. Returns an error if running the verification task
 // fails.
-func Run(rootDir string, projectParam ProjectParam, stdout io.Writer) error {
-	_, err := runGenerate(rootDir, projectParam, stdout)
+func Run(rootDir string, projectParam ProjectParam, stdout io.Writer, opts ...Option) error {
+	_, err := runGenerate(rootDir, projectParam, stdout, newOptions(opts))
 	return err
 }
 
@@ -40,8 +40,8 @@ func Run(rootDir string, projectParam ProjectParam, stdout io.Writer) error {
 // (that is, running the generator did not change the declared outputs), false otherwise. If verification is not
 // successful, the reason is written as output to the provided writer. Returns an error if an error is encountered when
 // running the verify task itself.
-func Verify(rootDir string, projectParam ProjectParam, stdout io.Writer) (bool, error) {
-	diff, err := runGenerate(rootDir, projectParam, stdout)
+func Verify(rootDir string, projectParam ProjectParam, stdout io.Writer, opts ...Option) (bool, error) {
+	diff, err := runGenerate(rootDir, projectParam, stdout, newOptions(opts))
 	if err != nil {
 		return false, err
 	}
@@ -68,151 +68,309 @@ func Verify(rootDir string, projectParam ProjectParam, stdout io.Writer) (bool,
 	return false, nil
 }
 
-func runGenerate(rootDir string, projectParam ProjectParam, stdout io.Writer) (map[string]ChecksumsDiff, error) {
+// CheckDeterminism runs every generator twice in succession and returns true if the second run of each generator
+// produced exactly the same output as its first run, false otherwise. If cleanOutputs is true, all of the paths matched
+// by a generator are removed before it is run for the second time. If the check is not successful, the paths that
+// differed between the runs and the differences in their content are written as output to the provided writer. Returns
+// an error if an error is encountered when running the check itself.
+func CheckDeterminism(rootDir string, projectParam ProjectParam, cleanOutputs bool, stdout io.Writer, opts ...Option) (bool, error) {
+	o := newOptions(opts)
+	order, err := projectParam.Generators.ExecutionOrder()
+	if err != nil {
+		return false, err
+	}
+
 	diffs := make(map[string]ChecksumsDiff)
-	for _, k := range projectParam.Generators.SortedKeys() {
+	contentDiffs := make(map[string]map[string]string)
+	for _, k := range order {
 		v := projectParam.Generators[k]
-		m := v.GenPaths
-		origChecksums, err := checksumsForMatchingPaths(rootDir, m)
+		s := newScanner(rootDir, projectParam, o, stdout, k)
+		if err := runGenerator(rootDir, v, stdout); err != nil {
+			return false, err
+		}
+		firstChecksums, err := s.scan()
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to compute checksums")
+			return false, errors.Wrapf(err, "failed to compute checksums after first run of generator %q", k)
 		}
-
-		genDir := path.Join(rootDir, v.GoGenDir)
-		cmd := exec.Command("go", "generate")
-		cmd.Dir = genDir
-		cmd.Stdout = stdout
-		cmd.Stderr = stdout
-
-		var envVars []string
-		for k, v := range projectParam.Generators[k].Environment {
-			envVars = append(envVars, fmt.Sprintf("%s=%v", k, v))
+		firstContents, err := firstChecksums.contents(rootDir)
+		if err != nil {
+			return false, err
 		}
-		cmd.Env = append(envVars, os.Environ()...)
 
-		if err := cmd.Run(); err != nil {
-			return nil, errors.Wrapf(err, "failed to run go generate in %q", genDir)
+		if cleanOutputs {
+			if err := firstChecksums.remove(rootDir); err != nil {
+				return false, err
+			}
 		}
-
-		newChecksums, err := checksumsForMatchingPaths(rootDir, m)
+		if err := runGenerator(rootDir, v, stdout); err != nil {
+			return false, err
+		}
+		secondChecksums, err := s.scan()
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to compute checksums")
+			return false, errors.Wrapf(err, "failed to compute checksums after second run of generator %q", k)
+		}
+		secondContents, err := secondChecksums.contents(rootDir)
+		if err != nil {
+			return false, err
 		}
 
-		diff := origChecksums.compare(newChecksums)
-		if len(diff) > 0 {
-			diffs[k] = diff
+		diff := firstChecksums.forGenerator(k, v).compare(secondChecksums.forGenerator(k, v), v.IgnoreMode)
+		if len(diff) == 0 {
+			continue
+		}
+		diffs[k] = diff
+		contentDiffs[k] = make(map[string]string)
+		for p := range diff {
+			if contentDiff := unifiedDiff(p+" (first run)", p+" (second run)", v.Normalize.Normalize(firstContents[p]), v.Normalize.Normalize(secondContents[p])); contentDiff != "" {
+				contentDiffs[k][p] = contentDiff
+			}
 		}
 	}
-	return diffs, nil
-}
-
-type checksumSet map[string]*fileChecksumInfo
 
-type ChecksumsDiff map[string]string
+	if len(diffs) == 0 {
+		return true, nil
+	}
 
-func (c ChecksumsDiff) String() string {
 	var sortedKeys []string
-	for k := range c {
+	for k := range diffs {
 		sortedKeys = append(sortedKeys, k)
 	}
 	sort.Strings(sortedKeys)
 
-	var parts []string
+	var outputParts []string
+	outputParts = append(outputParts, fmt.Sprintf("Generators produced different output when run a second time: %v", sortedKeys))
 	for _, k := range sortedKeys {
-		parts = append(parts, fmt.Sprintf("%s: %s", k, c[k]))
+		outputParts = append(outputParts, fmt.Sprintf("  %s:", k))
+		for _, p := range diffs[k].sortedKeys() {
+			outputParts = append(outputParts, fmt.Sprintf("    %s: %s", p, diffs[k][p]))
+			contentDiff, ok := contentDiffs[k][p]
+			if !ok {
+				continue
+			}
+			for currLine := range strings.SplitSeq(contentDiff, "\n") {
+				outputParts = append(outputParts, fmt.Sprintf("      %s", currLine))
+			}
+		}
 	}
-	return strings.Join(parts, "\n")
+	_, _ = fmt.Fprintln(stdout, strings.Join(outputParts, "\n"))
+	return false, nil
 }
 
-func (c checksumSet) compare(other checksumSet) ChecksumsDiff {
-	diffs := make(map[string]string)
+// CheckOrderIndependence runs all of the generators in their regular execution order and then runs them again in an
+// order that is shuffled using the provided seed (while still honoring declared dependencies). Returns true if the
+// output of the shuffled run is the same as the output of the regular run, false otherwise. Because every generator is
+// expected to produce the same output regardless of the order in which it is run, differences between the runs are
+// evidence of dependencies between generators that have not been declared. If the check is not successful, the paths
+// that differed between the runs and the differences in their content are written as output to the provided writer.
+// Returns an error if an error is encountered when running the check itself.
+func CheckOrderIndependence(rootDir string, projectParam ProjectParam, seed int64, stdout io.Writer, opts ...Option) (bool, error) {
+	order, err := projectParam.Generators.ExecutionOrder()
+	if err != nil {
+		return false, err
+	}
+	shuffledOrder, err := projectParam.Generators.ShuffledExecutionOrder(rand.New(rand.NewPCG(uint64(seed), 0)))
+	if err != nil {
+		return false, err
+	}
+
+	s := newScanner(rootDir, projectParam, newOptions(opts), stdout, order...)
 
-	// determine missing and extra entries
-	for k := range c {
-		if _, ok := other[k]; !ok {
-			diffs[k] = "existed before, no longer exists"
+	// record the initial state of the outputs so that both runs start from the same state
+	initialState, err := newOutputSnapshot(rootDir, s)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to compute checksums before running generators")
+	}
+
+	for _, k := range order {
+		if err := runGenerator(rootDir, projectParam.Generators[k], stdout); err != nil {
+			return false, err
 		}
 	}
-	for k := range other {
-		if _, ok := c[k]; !ok {
-			diffs[k] = "did not exist before, now exists"
+	baselineChecksums, err := s.scan()
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to compute checksums after running generators in sorted order")
+	}
+	baselineContents, err := baselineChecksums.contents(rootDir)
+	if err != nil {
+		return false, err
+	}
+
+	if err := baselineChecksums.remove(rootDir); err != nil {
+		return false, err
+	}
+	if err := initialState.restore(rootDir); err != nil {
+		return false, err
+	}
+
+	_, _ = fmt.Fprintf(stdout, "Running generators in shuffled order (seed %d): %v\n", seed, shuffledOrder)
+	for _, k := range shuffledOrder {
+		if err := runGenerator(rootDir, projectParam.Generators[k], stdout); err != nil {
+			return false, err
 		}
 	}
+	shuffledChecksums, err := s.scan()
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to compute checksums after running generators in shuffled order")
+	}
+	shuffledContents, err := shuffledChecksums.contents(rootDir)
+	if err != nil {
+		return false, err
+	}
 
-	// compare content
-	for k, v := range c {
-		otherV, ok := other[k]
-		if !ok {
-			continue
+	// attribute every path that differs to the generators that declare it as output
+	diffs := make(map[string]ChecksumsDiff)
+	for _, k := range order {
+		v := projectParam.Generators[k]
+		if diff := baselineChecksums.forGenerator(k, v).compare(shuffledChecksums.forGenerator(k, v), v.IgnoreMode); len(diff) > 0 {
+			diffs[k] = diff
 		}
+	}
+	if len(diffs) == 0 {
+		return true, nil
+	}
 
-		if v.isDir != otherV.isDir {
-			if v.isDir {
-				diffs[k] = "was previously a directory, is now a file"
-			} else {
-				diffs[k] = "was previously a file, is now a directory"
+	var sortedKeys []string
+	for k := range diffs {
+		sortedKeys = append(sortedKeys, k)
+	}
+	sort.Strings(sortedKeys)
+
+	var outputParts []string
+	outputParts = append(outputParts, fmt.Sprintf("Generators produced different output when run in shuffled order (seed %d), which indicates undeclared dependencies between generators: %v", seed, sortedKeys))
+	for _, k := range sortedKeys {
+		outputParts = append(outputParts, fmt.Sprintf("  %s:", k))
+		normalizer := projectParam.Generators[k].Normalize
+		for _, p := range diffs[k].sortedKeys() {
+			outputParts = append(outputParts, fmt.Sprintf("    %s: %s", p, diffs[k][p]))
+			contentDiff := unifiedDiff(p+" (sorted order)", p+" (shuffled order)", normalizer.Normalize(baselineContents[p]), normalizer.Normalize(shuffledContents[p]))
+			for currLine := range strings.SplitSeq(contentDiff, "\n") {
+				if currLine == "" {
+					continue
+				}
+				outputParts = append(outputParts, fmt.Sprintf("      %s", currLine))
 			}
-			continue
-		}
-		if v.sha256checksum != otherV.sha256checksum {
-			diffs[k] = fmt.Sprintf("previously had checksum %s, now has checksum %s", v.sha256checksum, otherV.sha256checksum)
 		}
 	}
-
-	return diffs
+	_, _ = fmt.Fprintln(stdout, strings.Join(outputParts, "\n"))
+	return false, nil
 }
 
-type fileChecksumInfo struct {
-	path           string
-	isDir          bool
-	sha256checksum string
-}
+func runGenerate(rootDir string, projectParam ProjectParam, stdout io.Writer, o *options) (map[string]ChecksumsDiff, error) {
+	order, err := projectParam.Generators.ExecutionOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	// the checksums computed after running a generator are the checksums before running the next generator, so every
+	// generator only requires a single scan
+	s := newScanner(rootDir, projectParam, o, stdout, order...)
+	checksums, err := s.scan()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to compute checksums before running generators")
+	}
 
-func checksumsForMatchingPaths(rootDir string, m matcher.Matcher) (checksumSet, error) {
-	pathsToChecksums := make(map[string]*fileChecksumInfo)
-	if err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
-		relPath, err := filepath.Rel(rootDir, path)
+	diffs := make(map[string]ChecksumsDiff)
+	problems := make(outputProblems)
+	pkgDirs := make(map[string][]string)
+	compileErrs := make(compileErrors)
+	for _, k := range order {
+		v := projectParam.Generators[k]
+		if err := runGenerator(rootDir, v, stdout); err != nil {
+			return nil, err
+		}
+
+		newChecksums, err := s.scan()
 		if err != nil {
-			return err
+			return nil, errors.Wrapf(err, "failed to compute checksums after running generator %q", k)
+		}
+		rewritten, err := checkOutputs(rootDir, k, v, newChecksums.forGenerator(k, v).goFiles(), problems)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to check output of generator %q", k)
+		}
+		if rewritten {
+			// files rewritten by the output checks are part of the output of the generator
+			if newChecksums, err = s.scan(); err != nil {
+				return nil, errors.Wrapf(err, "failed to compute checksums after formatting output of generator %q", k)
+			}
 		}
-		if m.Match(relPath) {
-			checksum, err := newChecksum(path, info)
-			if err != nil {
-				return err
+		if o.compileCheck != nil {
+			pkgDirs[k] = packageDirs(newChecksums.forGenerator(k, v).goFiles())
+			if !o.compileCheck.once {
+				if err := o.compileCheck.run(rootDir, projectParam, []string{k}, pkgDirs, v.envVars(), compileErrs); err != nil {
+					return nil, errors.Wrapf(err, "failed to check that output of generator %q compiles", k)
+				}
 			}
-			pathsToChecksums[relPath] = checksum
 		}
-		return nil
-	}); err != nil {
-		return nil, errors.Wrapf(err, "failed to walk directory %q", rootDir)
+
+		diff := checksums.forGenerator(k, v).compare(newChecksums.forGenerator(k, v), v.IgnoreMode)
+		if len(diff) > 0 {
+			diffs[k] = diff
+		}
+		checksums = newChecksums
+	}
+	if o.compileCheck != nil && o.compileCheck.once {
+		if err := o.compileCheck.run(rootDir, projectParam, order, pkgDirs, nil, compileErrs); err != nil {
+			return nil, errors.Wrapf(err, "failed to check that output of generators compiles")
+		}
 	}
-	return pathsToChecksums, nil
+
+	var errMsgs []string
+	for _, err := range []error{problems.error(), compileErrs.error()} {
+		if err != nil {
+			errMsgs = append(errMsgs, err.Error())
+		}
+	}
+	if len(errMsgs) > 0 {
+		return nil, errors.New(strings.Join(errMsgs, "\n"))
+	}
+	return diffs, nil
 }
 
-func newChecksum(filePath string, info os.FileInfo) (*fileChecksumInfo, error) {
-	f, err := os.Open(filePath)
-	if err != nil {
-		return nil, err
+// runGenerator runs the command of the provided generator.
+func runGenerator(rootDir string, param GeneratorParam, stdout io.Writer) error {
+	ctx := context.Background()
+	if param.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, param.Timeout)
+		defer cancel()
+	}
+	cmd := generatorCmd(ctx, rootDir, param)
+	cmd.Stdout = stdout
+	cmd.Stderr = stdout
+	// processes started by the command are not killed when it times out, so do not wait for them to close its output
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return errors.Errorf("failed to run %s in %q: timed out after %s", strings.Join(cmd.Args, " "), cmd.Dir, param.Timeout)
+		}
+		return errors.Wrapf(err, "failed to run %s in %q", strings.Join(cmd.Args, " "), cmd.Dir)
 	}
-	defer func() {
-		// file is opened for reading only, so safe to ignore errors on close
-		_ = f.Close()
-	}()
+	return nil
+}
 
-	if info.IsDir() {
-		return &fileChecksumInfo{
-			path:  filePath,
-			isDir: true,
-		}, nil
+// generatorCmd returns the command for the provided generator, which is "go generate" followed by the flags of the
+// generator unless the generator specifies its own command. The provided additional flags are inserted directly after
+// the command and before the flags of the generator, since the flags of the generator may end with arguments (such as
+// package patterns) after which flags are not recognized. The environment of the command consists of the environment
+// variables specified by the generator followed by the environment of the current process, so variables set in the
+// environment of the current process take precedence.
+func generatorCmd(ctx context.Context, rootDir string, param GeneratorParam, cmdFlags ...string) *exec.Cmd {
+	command := []string{"go", "generate"}
+	if len(param.Command) > 0 {
+		command = param.Command
 	}
+	cmdArgs := append(append(append([]string{}, command[1:]...), cmdFlags...), param.Flags...)
+	cmd := exec.CommandContext(ctx, command[0], cmdArgs...)
+	cmd.Dir = path.Join(rootDir, param.GoGenDir)
+	cmd.Env = append(param.envVars(), os.Environ()...)
+	return cmd
+}
 
-	h := sha256.New()
-	if _, err := io.Copy(h, f); err != nil {
-		return nil, err
+// envVars returns the environment variables specified by the generator in the "KEY=value" form.
+func (p GeneratorParam) envVars() []string {
+	var envVars []string
+	for k, v := range p.Environment {
+		envVars = append(envVars, fmt.Sprintf("%s=%v", k, v))
 	}
-	return &fileChecksumInfo{
-		path:           filePath,
-		sha256checksum: fmt.Sprintf("%x", h.Sum(nil)),
-	}, nil
+	return envVars
 }
@@ -17,14 +17,17 @@ package gogenerate_test
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"testing"
+	"time"
 
 	"github.com/nmiyake/pkg/dirs"
 	"github.com/nmiyake/pkg/gofiles"
 	"github.com/palantir/go-generate/gogenerate"
 	"github.com/palantir/go-generate/gogenerate/config"
+	"github.com/palantir/pkg/matcher"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v2"
@@ -143,6 +146,98 @@ generators:
 	assert.Equal(t, "test-val", string(outputTxt))
 }
 
+func TestRunCommandAndFlags(t *testing.T) {
+	for currCaseNum, currCase := range []struct {
+		name       string
+		command    []string
+		flags      []string
+		wantOutput string
+	}{
+		{
+			name:       "flags are provided to go generate",
+			flags:      []string{"-run", "second"},
+			wantOutput: "second",
+		},
+		{
+			name:       "flags are provided to command",
+			command:    []string{"go", "run", "generator_main.go"},
+			flags:      []string{"from-command"},
+			wantOutput: "from-command",
+		},
+	} {
+		testDir, cleanup, err := dirs.TempDir(".", "")
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		_, err = gofiles.Write(testDir, []gofiles.GoFileSpec{
+			{
+				RelPath: "gen/testbar.go",
+				Src: `package testbar
+
+//go:generate go run generator_main.go first
+//go:generate go run generator_main.go second
+`,
+			},
+			{
+				RelPath: "gen/generator_main.go",
+				Src: `// +build ignore
+
+package main
+
+import (
+	"os"
+)
+
+func main() {
+	if err := os.WriteFile("output.txt", []byte(os.Args[1]), 0644); err != nil {
+		panic(err)
+	}
+}
+`,
+			},
+		})
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		err = gogenerate.Run(testDir, gogenerate.ProjectParam{
+			Generators: gogenerate.Generators{
+				"foo": gogenerate.GeneratorParam{
+					GoGenDir: "gen",
+					Command:  currCase.command,
+					Flags:    currCase.flags,
+					GenPaths: matcher.Path("gen/output.txt"),
+				},
+			},
+		}, io.Discard)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		outputTxt, err := os.ReadFile(path.Join(testDir, "gen", "output.txt"))
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		assert.Equal(t, currCase.wantOutput, string(outputTxt), "Case %d: %s", currCaseNum, currCase.name)
+
+		cleanup()
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	testDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	err = os.Mkdir(path.Join(testDir, "gen"), 0755)
+	require.NoError(t, err)
+
+	err = gogenerate.Run(testDir, gogenerate.ProjectParam{
+		Generators: gogenerate.Generators{
+			"foo": gogenerate.GeneratorParam{
+				GoGenDir: "gen",
+				Command:  []string{"sleep", "10"},
+				GenPaths: matcher.Path("gen/output.txt"),
+				Timeout:  100 * time.Millisecond,
+			},
+		},
+	}, io.Discard)
+	assert.EqualError(t, err, fmt.Sprintf(`failed to run sleep 10 in %q: timed out after 100ms`, path.Join(testDir, "gen")))
+}
+
 func TestVerify(t *testing.T) {
 	testDir, cleanup, err := dirs.TempDir(".", "")
 	defer cleanup()
@@ -413,6 +508,176 @@ func main() {
 			wantOutput: `Generators produced output that differed from what already exists: [foo]
   foo:
     gen/output.txt: previously had checksum 0fd6feace2703f1be2b4d05ef9931b70627e46a0dcd5c32acc460e392eb0c537, now has checksum 380a300b764683667309818ff127a401c6ea6ab1959f386fe0f05505d660ba37
+`,
+		},
+		{
+			name: "generated output retargets symlink to file with same content",
+			configYML: `
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "gen/output.txt"
+`,
+			gofiles: []gofiles.GoFileSpec{
+				{
+					RelPath: "gen/testbar.go",
+					Src: `package testbar
+
+//go:generate go run generator_main.go
+`,
+				},
+				{
+					RelPath: "gen/generator_main.go",
+					Src: `// +build ignore
+
+package main
+
+import (
+	"os"
+)
+
+func main() {
+	if err := os.Remove("output.txt"); err != nil {
+		panic(err)
+	}
+	if err := os.Symlink("b.txt", "output.txt"); err != nil {
+		panic(err)
+	}
+}
+`,
+				},
+			},
+			initialState: func(caseNum int, caseName, testDir string) {
+				for _, name := range []string{"a.txt", "b.txt"} {
+					err := os.WriteFile(path.Join(testDir, "gen", name), []byte("foo-output"), 0644)
+					require.NoError(t, err, "Case %d: %s", caseNum, caseName)
+				}
+				err := os.Symlink("a.txt", path.Join(testDir, "gen", "output.txt"))
+				require.NoError(t, err, "Case %d: %s", caseNum, caseName)
+			},
+			wantOutput: `Generators produced output that differed from what already exists: [foo]
+  foo:
+    gen/output.txt: previously linked to "a.txt", now links to "b.txt"
+`,
+		},
+		{
+			name: "generated output retargets dangling symlink",
+			configYML: `
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "gen/output.txt"
+`,
+			gofiles: []gofiles.GoFileSpec{
+				{
+					RelPath: "gen/testbar.go",
+					Src: `package testbar
+
+//go:generate go run generator_main.go
+`,
+				},
+				{
+					RelPath: "gen/generator_main.go",
+					Src: `// +build ignore
+
+package main
+
+import (
+	"os"
+)
+
+func main() {
+	if err := os.Remove("output.txt"); err != nil {
+		panic(err)
+	}
+	if err := os.Symlink("missing-b.txt", "output.txt"); err != nil {
+		panic(err)
+	}
+}
+`,
+				},
+			},
+			initialState: func(caseNum int, caseName, testDir string) {
+				err := os.Symlink("missing-a.txt", path.Join(testDir, "gen", "output.txt"))
+				require.NoError(t, err, "Case %d: %s", caseNum, caseName)
+			},
+			wantOutput: `Generators produced output that differed from what already exists: [foo]
+  foo:
+    gen/output.txt: previously linked to "missing-a.txt", now links to "missing-b.txt"
+`,
+		},
+		{
+			name: "generated output changes file to symlink",
+			configYML: `
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "gen/output.txt"
+`,
+			gofiles: []gofiles.GoFileSpec{
+				{
+					RelPath: "gen/testbar.go",
+					Src: `package testbar
+
+//go:generate go run generator_main.go
+`,
+				},
+				{
+					RelPath: "gen/generator_main.go",
+					Src: `// +build ignore
+
+package main
+
+import (
+	"os"
+)
+
+func main() {
+	if err := os.Remove("output.txt"); err != nil {
+		panic(err)
+	}
+	if err := os.Symlink("a.txt", "output.txt"); err != nil {
+		panic(err)
+	}
+}
+`,
+				},
+			},
+			initialState: func(caseNum int, caseName, testDir string) {
+				for _, name := range []string{"a.txt", "output.txt"} {
+					err := os.WriteFile(path.Join(testDir, "gen", name), []byte("foo-output"), 0644)
+					require.NoError(t, err, "Case %d: %s", caseNum, caseName)
+				}
+			},
+			wantOutput: `Generators produced output that differed from what already exists: [foo]
+  foo:
+    gen/output.txt: was previously a file, is now a symlink
+`,
+		},
+		{
+			name: "generated output changes mode",
+			configYML: `
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "gen/script.sh"
+`,
+			gofiles: modeChangingGeneratorSpecs,
+			initialState: func(caseNum int, caseName, testDir string) {
+				err := os.WriteFile(path.Join(testDir, "gen", "script.sh"), []byte("#!/bin/sh"), 0755)
+				require.NoError(t, err, "Case %d: %s", caseNum, caseName)
+			},
+			wantOutput: `Generators produced output that differed from what already exists: [foo]
+  foo:
+    gen/script.sh: previously had mode 0755, now has mode 0644
 `,
 		},
 	} {
@@ -438,3 +703,1103 @@ func main() {
 		assert.Equal(t, currCase.wantOutput, outBuf.String(), "Case %d: %s", currCaseNum, currCase.name)
 	}
 }
+
+func TestCheckDeterminism(t *testing.T) {
+	testDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	const configYML = `
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "gen/output.txt"
+`
+	for currCaseNum, currCase := range []struct {
+		name         string
+		generatorSrc string
+		cleanOutputs bool
+		wantOK       bool
+		wantOutput   string
+	}{
+		{
+			name: "deterministic generator",
+			generatorSrc: `// +build ignore
+
+package main
+
+import (
+	"io/ioutil"
+)
+
+func main() {
+	if err := ioutil.WriteFile("output.txt", []byte("foo-output"), 0644); err != nil {
+		panic(err)
+	}
+}
+`,
+			wantOK: true,
+		},
+		{
+			name: "generator that depends on state outside of its outputs",
+			generatorSrc: `// +build ignore
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+)
+
+func main() {
+	count := 0
+	if bytes, err := ioutil.ReadFile("count.txt"); err == nil {
+		count, _ = strconv.Atoi(string(bytes))
+	}
+	if err := ioutil.WriteFile("count.txt", []byte(strconv.Itoa(count+1)), 0644); err != nil {
+		panic(err)
+	}
+	if err := ioutil.WriteFile("output.txt", []byte(fmt.Sprintf("header\nrun %d\nfooter\n", count)), 0644); err != nil {
+		panic(err)
+	}
+}
+`,
+			wantOK: false,
+			wantOutput: `Generators produced different output when run a second time: [foo]
+  foo:
+    gen/output.txt: previously had checksum 3245d9e2ed84af98e1432fe6b515502716e2096bbe511487d5fd4d9e98e64d08, now has checksum 7a92cd244b303e7d7f3796ade540518d973251b0395fb110c71c979630ddc7f4
+      --- gen/output.txt (first run)
+      +++ gen/output.txt (second run)
+      @@ -1,3 +1,3 @@
+       header
+      -run 0
+      +run 1
+       footer
+`,
+		},
+		{
+			name:         "generator that appends to existing output is not deterministic",
+			generatorSrc: appendingGeneratorSrc,
+			wantOK:       false,
+			wantOutput: `Generators produced different output when run a second time: [foo]
+  foo:
+    gen/output.txt: previously had checksum fc3ba6465558978cc7938d9404039fe4423db4062ab38c0415b793f005fff149, now has checksum b6765016de971190575842fac94493508051a41d90db65d01a01272c18d71d60
+      --- gen/output.txt (first run)
+      +++ gen/output.txt (second run)
+      @@ -1 +1,2 @@
+       appended
+      +appended
+`,
+		},
+		{
+			name:         "generator that appends to existing output is deterministic with clean outputs",
+			generatorSrc: appendingGeneratorSrc,
+			cleanOutputs: true,
+			wantOK:       true,
+		},
+	} {
+		currCaseDir, err := os.MkdirTemp(testDir, "")
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		_, err = gofiles.Write(currCaseDir, []gofiles.GoFileSpec{
+			{
+				RelPath: "gen/testbar.go",
+				Src: `package testbar
+
+//go:generate go run generator_main.go
+`,
+			},
+			{
+				RelPath: "gen/generator_main.go",
+				Src:     currCase.generatorSrc,
+			},
+		})
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		var cfg config.ProjectConfig
+		err = yaml.Unmarshal([]byte(configYML), &cfg)
+		require.NoError(t, err)
+
+		outBuf := &bytes.Buffer{}
+		ok, err := gogenerate.CheckDeterminism(currCaseDir, cfg.ToParam(), currCase.cleanOutputs, outBuf)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		assert.Equal(t, currCase.wantOK, ok, "Case %d: %s", currCaseNum, currCase.name)
+		assert.Equal(t, currCase.wantOutput, outBuf.String(), "Case %d: %s", currCaseNum, currCase.name)
+	}
+}
+
+const appendingGeneratorSrc = `// +build ignore
+
+package main
+
+import (
+	"os"
+)
+
+func main() {
+	f, err := os.OpenFile("output.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString("appended\n"); err != nil {
+		panic(err)
+	}
+}
+`
+
+func TestCheckOrderIndependence(t *testing.T) {
+	testDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	// generator "b" copies the output of generator "a", so it only produces the correct output if "a" runs first
+	specs := []gofiles.GoFileSpec{
+		{
+			RelPath: "a/a.go",
+			Src: `package a
+
+//go:generate go run generator_main.go
+`,
+		},
+		{
+			RelPath: "a/generator_main.go",
+			Src: `// +build ignore
+
+package main
+
+import (
+	"io/ioutil"
+)
+
+func main() {
+	if err := ioutil.WriteFile("output.txt", []byte("v2\n"), 0644); err != nil {
+		panic(err)
+	}
+}
+`,
+		},
+		{
+			RelPath: "b/b.go",
+			Src: `package b
+
+//go:generate go run generator_main.go
+`,
+		},
+		{
+			RelPath: "b/generator_main.go",
+			Src: `// +build ignore
+
+package main
+
+import (
+	"io/ioutil"
+)
+
+func main() {
+	content, err := ioutil.ReadFile("../a/output.txt")
+	if err != nil {
+		panic(err)
+	}
+	if err := ioutil.WriteFile("output.txt", content, 0644); err != nil {
+		panic(err)
+	}
+}
+`,
+		},
+	}
+
+	for currCaseNum, currCase := range []struct {
+		name       string
+		configYML  string
+		wantOK     bool
+		wantOutput string
+	}{
+		{
+			name: "undeclared dependency",
+			configYML: `
+generators:
+  a:
+    go-generate-dir: a
+    gen-paths:
+      paths:
+        - "a/output.txt"
+  b:
+    go-generate-dir: b
+    gen-paths:
+      paths:
+        - "b/output.txt"
+`,
+			wantOK: false,
+			wantOutput: `Running generators in shuffled order (seed 1): [b a]
+Generators produced different output when run in shuffled order (seed 1), which indicates undeclared dependencies between generators: [b]
+  b:
+    b/output.txt: previously had checksum 81db67b6a5702b9b68f0016f061c409bf3fb16d062fc854d1b424bb4e9c28c56, now has checksum 2d27fbdf4e8ca207afbfa388ca9172fbcc6c70e534af2476b3b704f87debadcf
+      --- b/output.txt (sorted order)
+      +++ b/output.txt (shuffled order)
+      @@ -1 +1 @@
+      -v2
+      +v1
+`,
+		},
+		{
+			name: "declared dependency",
+			configYML: `
+generators:
+  a:
+    go-generate-dir: a
+    gen-paths:
+      paths:
+        - "a/output.txt"
+  b:
+    go-generate-dir: b
+    gen-paths:
+      paths:
+        - "b/output.txt"
+    depends-on:
+      - a
+`,
+			wantOK: true,
+			wantOutput: `Running generators in shuffled order (seed 1): [a b]
+`,
+		},
+	} {
+		currCaseDir, err := os.MkdirTemp(testDir, "")
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		_, err = gofiles.Write(currCaseDir, specs)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		err = os.WriteFile(path.Join(currCaseDir, "a", "output.txt"), []byte("v1\n"), 0644)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		err = os.WriteFile(path.Join(currCaseDir, "b", "output.txt"), []byte("v1\n"), 0644)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		var cfg config.ProjectConfig
+		err = yaml.Unmarshal([]byte(currCase.configYML), &cfg)
+		require.NoError(t, err)
+
+		outBuf := &bytes.Buffer{}
+		ok, err := gogenerate.CheckOrderIndependence(currCaseDir, cfg.ToParam(), 1, outBuf)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		assert.Equal(t, currCase.wantOK, ok, "Case %d: %s", currCaseNum, currCase.name)
+		assert.Equal(t, currCase.wantOutput, outBuf.String(), "Case %d: %s", currCaseNum, currCase.name)
+	}
+}
+
+func TestVerifyExclude(t *testing.T) {
+	testDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	specs := []gofiles.GoFileSpec{
+		{
+			RelPath: "gen/testbar.go",
+			Src: `package testbar
+
+//go:generate go run generator_main.go
+`,
+		},
+		{
+			RelPath: "gen/generator_main.go",
+			Src: `// +build ignore
+
+package main
+
+import (
+	"io/ioutil"
+)
+
+func main() {
+	if err := ioutil.WriteFile("generated/output.txt", []byte("foo-output"), 0644); err != nil {
+		panic(err)
+	}
+	if err := ioutil.WriteFile("excluded/output.txt", []byte("foo-output"), 0644); err != nil {
+		panic(err)
+	}
+}
+`,
+		},
+	}
+	_, err = gofiles.Write(testDir, specs)
+	require.NoError(t, err)
+	for _, dir := range []string{"generated", "excluded"} {
+		err = os.MkdirAll(path.Join(testDir, "gen", dir), 0755)
+		require.NoError(t, err)
+	}
+	err = os.WriteFile(path.Join(testDir, "gen", "generated", "output.txt"), []byte("foo-output"), 0644)
+	require.NoError(t, err)
+	err = os.WriteFile(path.Join(testDir, "gen", "excluded", "output.txt"), []byte("stale-output"), 0644)
+	require.NoError(t, err)
+
+	const configYML = `
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      names:
+        - "output.txt"
+exclude:
+  paths:
+    - "gen/excluded"
+`
+	var cfg config.ProjectConfig
+	err = yaml.Unmarshal([]byte(configYML), &cfg)
+	require.NoError(t, err)
+
+	outBuf := &bytes.Buffer{}
+	verifyOK, err := gogenerate.Verify(testDir, cfg.ToParam(), outBuf)
+	require.NoError(t, err)
+	assert.True(t, verifyOK, outBuf.String())
+}
+
+func TestVerifyParanoid(t *testing.T) {
+	testDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	// generator rewrites its output with content of the same size and restores the previous modification time, so
+	// the change can only be detected by hashing the content of the file
+	specs := []gofiles.GoFileSpec{
+		{
+			RelPath: "gen/testbar.go",
+			Src: `package testbar
+
+//go:generate go run generator_main.go
+`,
+		},
+		{
+			RelPath: "gen/generator_main.go",
+			Src: `// +build ignore
+
+package main
+
+import (
+	"os"
+)
+
+func main() {
+	fi, err := os.Stat("output.txt")
+	if err != nil {
+		panic(err)
+	}
+	f, err := os.OpenFile("output.txt", os.O_WRONLY, 0)
+	if err != nil {
+		panic(err)
+	}
+	if _, err := f.WriteString("new-output"); err != nil {
+		panic(err)
+	}
+	if err := f.Close(); err != nil {
+		panic(err)
+	}
+	if err := os.Chtimes("output.txt", fi.ModTime(), fi.ModTime()); err != nil {
+		panic(err)
+	}
+}
+`,
+		},
+	}
+
+	const configYML = `
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "gen/output.txt"
+`
+	var cfg config.ProjectConfig
+	err = yaml.Unmarshal([]byte(configYML), &cfg)
+	require.NoError(t, err)
+
+	for currCaseNum, currCase := range []struct {
+		name   string
+		opts   []gogenerate.Option
+		wantOK bool
+	}{
+		{
+			name:   "checksum of file with unchanged metadata is reused",
+			wantOK: true,
+		},
+		{
+			name:   "paranoid verification hashes every file",
+			opts:   []gogenerate.Option{gogenerate.Paranoid()},
+			wantOK: false,
+		},
+	} {
+		currCaseDir, err := os.MkdirTemp(testDir, "")
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		_, err = gofiles.Write(currCaseDir, specs)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		outputPath := path.Join(currCaseDir, "gen", "output.txt")
+		err = os.WriteFile(outputPath, []byte("old-output"), 0644)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		oldModTime := time.Now().Add(-time.Hour)
+		err = os.Chtimes(outputPath, oldModTime, oldModTime)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		verifyOK, err := gogenerate.Verify(currCaseDir, cfg.ToParam(), io.Discard, currCase.opts...)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		assert.Equal(t, currCase.wantOK, verifyOK, "Case %d: %s", currCaseNum, currCase.name)
+	}
+}
+
+func TestVerifyReusedAndHashedChecksums(t *testing.T) {
+	testDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	// generator rewrites the "a" files and leaves the "b" files untouched, so the checksums of the "b" files are reused
+	// while the "a" files are hashed
+	_, err = gofiles.Write(testDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "gen/testbar.go",
+			Src: `package testbar
+
+//go:generate go run generator_main.go
+`,
+		},
+		{
+			RelPath: "gen/generator_main.go",
+			Src: `// +build ignore
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	for i := 0; i < 20; i++ {
+		if err := os.WriteFile(fmt.Sprintf("output/a-%02d.txt", i), []byte("new-output"), 0644); err != nil {
+			panic(err)
+		}
+	}
+}
+`,
+		},
+	})
+	require.NoError(t, err)
+
+	oldModTime := time.Now().Add(-time.Hour)
+	err = os.MkdirAll(path.Join(testDir, "gen", "output"), 0755)
+	require.NoError(t, err)
+	for i := 0; i < 20; i++ {
+		for _, prefix := range []string{"a", "b"} {
+			outputPath := path.Join(testDir, "gen", "output", fmt.Sprintf("%s-%02d.txt", prefix, i))
+			err = os.WriteFile(outputPath, []byte("old-output"), 0644)
+			require.NoError(t, err)
+			err = os.Chtimes(outputPath, oldModTime, oldModTime)
+			require.NoError(t, err)
+		}
+	}
+
+	var cfg config.ProjectConfig
+	err = yaml.Unmarshal([]byte(`
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "gen/output"
+`), &cfg)
+	require.NoError(t, err)
+
+	outBuf := &bytes.Buffer{}
+	verifyOK, err := gogenerate.Verify(testDir, cfg.ToParam(), outBuf)
+	require.NoError(t, err)
+	assert.False(t, verifyOK)
+	for i := 0; i < 20; i++ {
+		assert.Contains(t, outBuf.String(), fmt.Sprintf("gen/output/a-%02d.txt: previously had checksum", i))
+		assert.NotContains(t, outBuf.String(), fmt.Sprintf("gen/output/b-%02d.txt", i))
+	}
+}
+
+func TestVerifyScanErrors(t *testing.T) {
+	testDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	specs := []gofiles.GoFileSpec{
+		{
+			RelPath: "gen/testbar.go",
+			Src: `package testbar
+
+//go:generate go run generator_main.go
+`,
+		},
+		{
+			RelPath: "gen/generator_main.go",
+			Src: `// +build ignore
+
+package main
+
+import (
+	"io/ioutil"
+)
+
+func main() {
+	if err := ioutil.WriteFile("generated/output.txt", []byte("foo-output"), 0644); err != nil {
+		panic(err)
+	}
+}
+`,
+		},
+	}
+
+	for currCaseNum, currCase := range []struct {
+		name         string
+		skipIfRoot   bool
+		genPathsCfg  string
+		scanErrorCfg string
+		initialState func(caseDir string)
+		wantErr      string
+		wantOutput   string
+	}{
+		{
+			name: "dangling symlink is recorded by its target",
+			initialState: func(caseDir string) {
+				err := os.Symlink("missing.txt", path.Join(caseDir, "gen", "generated", "link.txt"))
+				require.NoError(t, err)
+			},
+			wantOutput: `^$`,
+		},
+		{
+			name:       "unreadable file fails by default",
+			skipIfRoot: true,
+			initialState: func(caseDir string) {
+				err := os.WriteFile(path.Join(caseDir, "gen", "generated", "unreadable.txt"), []byte("secret"), 0000)
+				require.NoError(t, err)
+			},
+			wantErr: `^failed to compute checksums before running generators: generator "foo": "gen/generated/unreadable.txt" \(permission-denied\): open .+: permission denied$`,
+		},
+		{
+			name:       "unreadable directory is skipped",
+			skipIfRoot: true,
+			scanErrorCfg: `
+scan-errors:
+  permission-denied: skip
+`,
+			initialState: func(caseDir string) {
+				err := os.Mkdir(path.Join(caseDir, "gen", "generated", "unreadable"), 0000)
+				require.NoError(t, err)
+			},
+			wantOutput: `^$`,
+		},
+		{
+			name:       "unreadable directory fails if gen-paths cannot be bounded",
+			skipIfRoot: true,
+			// the unreadable directory could contain a file matched by the name pattern
+			genPathsCfg: `
+      names:
+        - "^output\\.txt$"
+`,
+			initialState: func(caseDir string) {
+				err := os.Mkdir(path.Join(caseDir, "unreadable"), 0000)
+				require.NoError(t, err)
+			},
+			wantErr: `^failed to compute checksums before running generators: generator "foo": "unreadable" \(permission-denied\): open .+: permission denied$`,
+		},
+	} {
+		if currCase.skipIfRoot && os.Geteuid() == 0 {
+			// permissions are not enforced for root
+			continue
+		}
+
+		currCaseDir, err := os.MkdirTemp(testDir, "")
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		_, err = gofiles.Write(currCaseDir, specs)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		err = os.MkdirAll(path.Join(currCaseDir, "gen", "generated"), 0755)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		err = os.WriteFile(path.Join(currCaseDir, "gen", "generated", "output.txt"), []byte("foo-output"), 0644)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		currCase.initialState(currCaseDir)
+
+		var cfg config.ProjectConfig
+		genPathsCfg := currCase.genPathsCfg
+		if genPathsCfg == "" {
+			genPathsCfg = `
+      paths:
+        - "gen/generated"
+`
+		}
+		err = yaml.Unmarshal([]byte(`
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:`+genPathsCfg+currCase.scanErrorCfg), &cfg)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		outBuf := &bytes.Buffer{}
+		verifyOK, err := gogenerate.Verify(currCaseDir, cfg.ToParam(), outBuf)
+		if currCase.wantErr != "" {
+			require.Error(t, err, "Case %d: %s", currCaseNum, currCase.name)
+			assert.Regexp(t, currCase.wantErr, err.Error(), "Case %d: %s", currCaseNum, currCase.name)
+			continue
+		}
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		assert.True(t, verifyOK, "Case %d: %s", currCaseNum, currCase.name)
+		assert.Regexp(t, currCase.wantOutput, outBuf.String(), "Case %d: %s", currCaseNum, currCase.name)
+	}
+}
+
+func TestVerifyIgnoreMode(t *testing.T) {
+	testDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(testDir, modeChangingGeneratorSpecs)
+	require.NoError(t, err)
+	err = os.WriteFile(path.Join(testDir, "gen", "script.sh"), []byte("#!/bin/sh"), 0755)
+	require.NoError(t, err)
+
+	const configYML = `
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "gen/script.sh"
+    ignore-mode: true
+`
+	var cfg config.ProjectConfig
+	err = yaml.Unmarshal([]byte(configYML), &cfg)
+	require.NoError(t, err)
+
+	outBuf := &bytes.Buffer{}
+	verifyOK, err := gogenerate.Verify(testDir, cfg.ToParam(), outBuf)
+	require.NoError(t, err)
+	assert.True(t, verifyOK, outBuf.String())
+}
+
+var modeChangingGeneratorSpecs = []gofiles.GoFileSpec{
+	{
+		RelPath: "gen/testbar.go",
+		Src: `package testbar
+
+//go:generate go run generator_main.go
+`,
+	},
+	{
+		RelPath: "gen/generator_main.go",
+		Src: `// +build ignore
+
+package main
+
+import (
+	"os"
+)
+
+func main() {
+	if err := os.Chmod("script.sh", 0644); err != nil {
+		panic(err)
+	}
+}
+`,
+	},
+}
+
+func TestVerifyNormalize(t *testing.T) {
+	testDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	specs := []gofiles.GoFileSpec{
+		{
+			RelPath: "gen/testbar.go",
+			Src: `package testbar
+
+//go:generate go run generator_main.go
+`,
+		},
+		{
+			RelPath: "gen/generator_main.go",
+			Src: `// +build ignore
+
+package main
+
+import (
+	"io/ioutil"
+	"time"
+)
+
+func main() {
+	if err := ioutil.WriteFile("output.txt", []byte("// generated at "+time.Now().String()+"\nfoo-output\n"), 0644); err != nil {
+		panic(err)
+	}
+}
+`,
+		},
+	}
+
+	for currCaseNum, currCase := range []struct {
+		name      string
+		configYML string
+		wantOK    bool
+	}{
+		{
+			name: "volatile line causes verification to fail",
+			configYML: `
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "gen/output.txt"
+`,
+			wantOK: false,
+		},
+		{
+			name: "volatile line is ignored",
+			configYML: `
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "gen/output.txt"
+    normalize:
+      ignore-lines:
+        - "^// generated at "
+`,
+			wantOK: true,
+		},
+	} {
+		currCaseDir, err := os.MkdirTemp(testDir, "")
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		_, err = gofiles.Write(currCaseDir, specs)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		err = os.WriteFile(path.Join(currCaseDir, "gen", "output.txt"), []byte("// generated at yesterday\nfoo-output\n"), 0644)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		var cfg config.ProjectConfig
+		err = yaml.Unmarshal([]byte(currCase.configYML), &cfg)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		outBuf := &bytes.Buffer{}
+		verifyOK, err := gogenerate.Verify(currCaseDir, cfg.ToParam(), outBuf)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		assert.Equal(t, currCase.wantOK, verifyOK, "Case %d: %s", currCaseNum, currCase.name)
+	}
+}
+
+func TestRunOutputChecks(t *testing.T) {
+	testDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	specs := []gofiles.GoFileSpec{
+		{
+			RelPath: "gen/testbar.go",
+			Src: `package testbar
+
+//go:generate go run generator_main.go
+`,
+		},
+		{
+			RelPath: "gen/generator_main.go",
+			Src: `// +build ignore
+
+package main
+
+import (
+	"os"
+)
+
+const header = "// Code generated by generator_main. DO NOT EDIT.\n\n"
+
+func main() {
+	if err := os.MkdirAll("out", 0755); err != nil {
+		panic(err)
+	}
+	for name, content := range map[string]string{
+		"with_header.go":    header + "package out\n",
+		"without_header.go": "package out\n",
+		"unformatted.go":    header + "package out\n\nvar  x = 1\n",
+		"output.txt":        "foo-output\n",
+	} {
+		if err := os.WriteFile("out/"+name, []byte(content), 0644); err != nil {
+			panic(err)
+		}
+	}
+	if os.Getenv("WRITE_INVALID") == "true" {
+		if err := os.WriteFile("out/invalid.go", []byte(header+"package out\n\nfunc {\n"), 0644); err != nil {
+			panic(err)
+		}
+	}
+}
+`,
+		},
+	}
+
+	for currCaseNum, currCase := range []struct {
+		name            string
+		configYML       string
+		wantError       string
+		wantUnformatted string
+	}{
+		{
+			name: "output is not checked by default",
+			configYML: `
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "gen/out"
+    environment:
+      WRITE_INVALID: "true"
+`,
+			wantUnformatted: "// Code generated by generator_main. DO NOT EDIT.\n\npackage out\n\nvar  x = 1\n",
+		},
+		{
+			name: "files without header cause failure if header is required",
+			configYML: `
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "gen/out"
+    output-checks:
+      require-generated-header: true
+`,
+			wantError: `Generators produced Go files that failed output checks: [foo]
+  foo:
+    gen/out/without_header.go: does not have the "// Code generated ... DO NOT EDIT." header`,
+			wantUnformatted: "// Code generated by generator_main. DO NOT EDIT.\n\npackage out\n\nvar  x = 1\n",
+		},
+		{
+			name: "unformatted and invalid files cause failure in check mode",
+			configYML: `
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "gen/out"
+    environment:
+      WRITE_INVALID: "true"
+    output-checks:
+      gofmt: check
+`,
+			wantError: `Generators produced Go files that failed output checks: [foo]
+  foo:
+    gen/out/invalid.go: is not valid Go: 5:6: expected 'IDENT', found '{'
+    gen/out/unformatted.go: is not formatted with gofmt`,
+			wantUnformatted: "// Code generated by generator_main. DO NOT EDIT.\n\npackage out\n\nvar  x = 1\n",
+		},
+		{
+			name: "unformatted files are formatted in fix mode",
+			configYML: `
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "gen/out"
+    output-checks:
+      gofmt: fix
+`,
+			wantUnformatted: "// Code generated by generator_main. DO NOT EDIT.\n\npackage out\n\nvar x = 1\n",
+		},
+		{
+			name: "invalid files cause failure in fix mode",
+			configYML: `
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "gen/out"
+    environment:
+      WRITE_INVALID: "true"
+    output-checks:
+      gofmt: fix
+`,
+			wantError: `Generators produced Go files that failed output checks: [foo]
+  foo:
+    gen/out/invalid.go: is not valid Go: 5:6: expected 'IDENT', found '{'`,
+			wantUnformatted: "// Code generated by generator_main. DO NOT EDIT.\n\npackage out\n\nvar x = 1\n",
+		},
+	} {
+		currCaseDir, err := os.MkdirTemp(testDir, "")
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		_, err = gofiles.Write(currCaseDir, specs)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		var cfg config.ProjectConfig
+		err = yaml.Unmarshal([]byte(currCase.configYML), &cfg)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		err = gogenerate.Run(currCaseDir, cfg.ToParam(), io.Discard)
+		if currCase.wantError == "" {
+			assert.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		} else {
+			assert.EqualError(t, err, currCase.wantError, "Case %d: %s", currCaseNum, currCase.name)
+		}
+
+		content, err := os.ReadFile(path.Join(currCaseDir, "gen", "out", "unformatted.go"))
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		assert.Equal(t, currCase.wantUnformatted, string(content), "Case %d: %s", currCaseNum, currCase.name)
+	}
+}
+
+func TestVerifyGofmtFix(t *testing.T) {
+	testDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(testDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "gen/testbar.go",
+			Src: `package testbar
+
+//go:generate go run generator_main.go
+`,
+		},
+		{
+			RelPath: "gen/generator_main.go",
+			Src: `// +build ignore
+
+package main
+
+import (
+	"os"
+)
+
+func main() {
+	if err := os.WriteFile("output.go", []byte("package testbar\n\nvar  x = 1\n"), 0644); err != nil {
+		panic(err)
+	}
+}
+`,
+		},
+	})
+	require.NoError(t, err)
+	err = os.WriteFile(path.Join(testDir, "gen", "output.go"), []byte("package testbar\n\nvar x = 1\n"), 0644)
+	require.NoError(t, err)
+
+	const configYML = `
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "gen/output.go"
+    output-checks:
+      gofmt: fix
+`
+	var cfg config.ProjectConfig
+	err = yaml.Unmarshal([]byte(configYML), &cfg)
+	require.NoError(t, err)
+
+	outBuf := &bytes.Buffer{}
+	verifyOK, err := gogenerate.Verify(testDir, cfg.ToParam(), outBuf)
+	require.NoError(t, err)
+	assert.True(t, verifyOK, outBuf.String())
+}
+
+func TestRunCompileCheck(t *testing.T) {
+	testDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	generatorMainSrc := func(content string) string {
+		return fmt.Sprintf(`// +build ignore
+
+package main
+
+import (
+	"os"
+)
+
+func main() {
+	if err := os.MkdirAll("out", 0755); err != nil {
+		panic(err)
+	}
+	if err := os.WriteFile("out/out.go", []byte(%q), 0644); err != nil {
+		panic(err)
+	}
+}
+`, content)
+	}
+	specs := []gofiles.GoFileSpec{
+		{
+			RelPath: "good/good.go",
+			Src: `package good
+
+//go:generate go run generator_main.go
+`,
+		},
+		{
+			RelPath: "good/generator_main.go",
+			Src:     generatorMainSrc("package out\n\nvar X = 1\n"),
+		},
+		{
+			RelPath: "bad/bad.go",
+			Src: `package bad
+
+//go:generate go run generator_main.go
+`,
+		},
+		{
+			RelPath: "bad/generator_main.go",
+			Src:     generatorMainSrc("package out\n\nvar X int = \"foo\"\n"),
+		},
+	}
+
+	const configYML = `
+generators:
+  good:
+    go-generate-dir: good
+    gen-paths:
+      paths:
+        - "good/out"
+  bad:
+    go-generate-dir: bad
+    gen-paths:
+      paths:
+        - "bad/out"
+`
+	var cfg config.ProjectConfig
+	err = yaml.Unmarshal([]byte(configYML), &cfg)
+	require.NoError(t, err)
+
+	for currCaseNum, currCase := range []struct {
+		name string
+		tool gogenerate.CompileCheckTool
+		once bool
+	}{
+		{
+			name: "packages are built after each generator",
+			tool: gogenerate.CompileCheckBuild,
+		},
+		{
+			name: "packages are vetted once after all generators",
+			tool: gogenerate.CompileCheckVet,
+			once: true,
+		},
+	} {
+		currCaseDir, err := os.MkdirTemp(testDir, "")
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		_, err = gofiles.Write(currCaseDir, specs)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		err = gogenerate.Run(currCaseDir, cfg.ToParam(), io.Discard, gogenerate.CompileCheck(currCase.tool, currCase.once))
+		require.Error(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		assert.Regexp(t, `^Generators produced Go packages that failed to compile: \[bad\]
+  bad:
+    (vet: )?bad/out/out.go:3:13: cannot use "foo" .+$`, err.Error(), "Case %d: %s", currCaseNum, currCase.name)
+	}
+}
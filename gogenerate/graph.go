@@ -0,0 +1,235 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// GraphFormat is the format in which the generator graph is written by WriteGraph.
+type GraphFormat string
+
+const (
+	// GraphFormatDOT writes the graph in the DOT language of Graphviz.
+	GraphFormatDOT GraphFormat = "dot"
+	// GraphFormatMermaid writes the graph as a Mermaid flowchart.
+	GraphFormatMermaid GraphFormat = "mermaid"
+)
+
+// GraphEdge is an ordering relationship between two generators: the From generator must be run before the To generator.
+type GraphEdge struct {
+	From string
+	To   string
+	// Implicit is true if the relationship is not declared using DependsOn, but the From generator currently produces a
+	// path that is an input of the To generator (a path in its GoGenDir or a path matched by its Inputs).
+	Implicit bool
+}
+
+// GraphEdges returns the ordering relationships between the generators sorted by From and To. An implicit edge is only
+// returned if there is no declared dependency between the same generators. Implicit edges are determined using the
+// paths that are currently matched by the GenPaths of every generator.
+func GraphEdges(rootDir string, projectParam ProjectParam) ([]GraphEdge, error) {
+	matched, err := matchedPaths(rootDir, projectParam)
+	if err != nil {
+		return nil, err
+	}
+
+	edges := make(map[GraphEdge]struct{})
+	for k, v := range projectParam.Generators {
+		for _, dep := range v.DependsOn {
+			edges[GraphEdge{From: dep, To: k}] = struct{}{}
+		}
+	}
+	for producer, producedPaths := range matched {
+		for consumer, consumerParam := range projectParam.Generators {
+			if consumer == producer {
+				continue
+			}
+			if _, ok := edges[GraphEdge{From: producer, To: consumer}]; ok {
+				continue
+			}
+			for _, producedPath := range producedPaths {
+				if consumerParam.reads(producedPath) {
+					edges[GraphEdge{From: producer, To: consumer, Implicit: true}] = struct{}{}
+					break
+				}
+			}
+		}
+	}
+
+	var sorted []GraphEdge
+	for edge := range edges {
+		sorted = append(sorted, edge)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].From != sorted[j].From {
+			return sorted[i].From < sorted[j].From
+		}
+		return sorted[i].To < sorted[j].To
+	})
+	return sorted, nil
+}
+
+// WriteGraph writes the graph of the generators and the edges returned by GraphEdges to the provided writer in the
+// provided format. Declared dependencies are drawn as solid edges and implicit ones as dashed edges. If showDirs is true,
+// the GoGenDir of every generator is drawn as a node that is linked to the generator. If showRoots is true, the
+// GenPathRoots of every generator (or the project directory if the entire project is walked) are drawn in the same
+// manner. Generators that share a directory or root are linked to the same node.
+func WriteGraph(rootDir string, projectParam ProjectParam, format GraphFormat, showDirs, showRoots bool, stdout io.Writer) error {
+	edges, err := GraphEdges(rootDir, projectParam)
+	if err != nil {
+		return err
+	}
+
+	g := &graph{}
+	for _, k := range projectParam.Generators.SortedKeys() {
+		g.generators = append(g.generators, k)
+	}
+	g.edges = edges
+	dirs := make(map[string]struct{})
+	roots := make(map[string]struct{})
+	for _, k := range g.generators {
+		v := projectParam.Generators[k]
+		if showDirs {
+			dir := path.Clean(filepath.ToSlash(v.GoGenDir))
+			dirs[dir] = struct{}{}
+			g.dirLinks = append(g.dirLinks, [2]string{k, dir})
+		}
+		if showRoots {
+			genPathRoots := v.GenPathRoots
+			if genPathRoots == nil {
+				genPathRoots = []string{"."}
+			}
+			for _, root := range genPathRoots {
+				root = path.Clean(filepath.ToSlash(root))
+				roots[root] = struct{}{}
+				g.rootLinks = append(g.rootLinks, [2]string{k, root})
+			}
+		}
+	}
+	g.dirs = sortedSet(dirs)
+	g.roots = sortedSet(roots)
+
+	var output string
+	switch format {
+	case GraphFormatDOT:
+		output = g.dot()
+	case GraphFormatMermaid:
+		output = g.mermaid()
+	default:
+		return errors.Errorf("invalid graph format %q: must be one of %q or %q", format, GraphFormatDOT, GraphFormatMermaid)
+	}
+	_, _ = fmt.Fprint(stdout, output)
+	return nil
+}
+
+type graph struct {
+	generators []string
+	edges      []GraphEdge
+	dirs       []string
+	roots      []string
+	// links from generators to their directories and roots
+	dirLinks  [][2]string
+	rootLinks [][2]string
+}
+
+func (g *graph) dot() string {
+	lines := []string{"digraph generators {", "  rankdir=LR;"}
+	for _, k := range g.generators {
+		lines = append(lines, fmt.Sprintf("  %q [shape=box];", k))
+	}
+	for _, dir := range g.dirs {
+		lines = append(lines, fmt.Sprintf("  %q [shape=folder, label=%q];", "dir:"+dir, dir))
+	}
+	for _, root := range g.roots {
+		lines = append(lines, fmt.Sprintf("  %q [shape=note, label=%q];", "root:"+root, root))
+	}
+	for _, edge := range g.edges {
+		attrs := ""
+		if edge.Implicit {
+			attrs = " [style=dashed]"
+		}
+		lines = append(lines, fmt.Sprintf("  %q -> %q%s;", edge.From, edge.To, attrs))
+	}
+	for _, link := range g.dirLinks {
+		lines = append(lines, fmt.Sprintf("  %q -> %q [style=dotted, arrowhead=none];", link[0], "dir:"+link[1]))
+	}
+	for _, link := range g.rootLinks {
+		lines = append(lines, fmt.Sprintf("  %q -> %q [style=dotted];", link[0], "root:"+link[1]))
+	}
+	lines = append(lines, "}")
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// mermaid returns the graph as a Mermaid flowchart. Because Mermaid node IDs cannot contain arbitrary characters, nodes
+// are identified by their kind and index and labeled with their names.
+func (g *graph) mermaid() string {
+	ids := make(map[string]string)
+	lines := []string{"flowchart LR"}
+	for i, k := range g.generators {
+		ids[k] = fmt.Sprintf("g%d", i)
+		lines = append(lines, fmt.Sprintf("  %s[%s]", ids[k], mermaidLabel(k)))
+	}
+	for i, dir := range g.dirs {
+		ids["dir:"+dir] = fmt.Sprintf("d%d", i)
+		lines = append(lines, fmt.Sprintf("  %s[(%s)]", ids["dir:"+dir], mermaidLabel(dir)))
+	}
+	for i, root := range g.roots {
+		ids["root:"+root] = fmt.Sprintf("r%d", i)
+		lines = append(lines, fmt.Sprintf("  %s>%s]", ids["root:"+root], mermaidLabel(root)))
+	}
+	for _, edge := range g.edges {
+		// edges may reference generators that do not exist if the configuration declares an unknown dependency
+		for _, k := range []string{edge.From, edge.To} {
+			if _, ok := ids[k]; !ok {
+				ids[k] = fmt.Sprintf("g%d", len(ids))
+				lines = append(lines, fmt.Sprintf("  %s[%s]", ids[k], mermaidLabel(k)))
+			}
+		}
+		arrow := "-->"
+		if edge.Implicit {
+			arrow = "-.->"
+		}
+		lines = append(lines, fmt.Sprintf("  %s %s %s", ids[edge.From], arrow, ids[edge.To]))
+	}
+	for _, link := range g.dirLinks {
+		lines = append(lines, fmt.Sprintf("  %s -.- %s", ids[link[0]], ids["dir:"+link[1]]))
+	}
+	for _, link := range g.rootLinks {
+		lines = append(lines, fmt.Sprintf("  %s -.-> %s", ids[link[0]], ids["root:"+link[1]]))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// mermaidLabel returns the provided text as a quoted Mermaid label.
+func mermaidLabel(text string) string {
+	return `"` + strings.ReplaceAll(text, `"`, "#quot;") + `"`
+}
+
+func sortedSet(set map[string]struct{}) []string {
+	var sorted []string
+	for k := range set {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,148 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/nmiyake/pkg/gofiles"
+	"github.com/palantir/go-generate/gogenerate"
+	"github.com/palantir/pkg/matcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteGraph(t *testing.T) {
+	for currCaseNum, currCase := range []struct {
+		name       string
+		format     gogenerate.GraphFormat
+		showDirs   bool
+		showRoots  bool
+		wantOutput string
+	}{
+		{
+			name:   "dot",
+			format: gogenerate.GraphFormatDOT,
+			wantOutput: `digraph generators {
+  rankdir=LR;
+  "docs" [shape=box];
+  "mocks" [shape=box];
+  "proto" [shape=box];
+  "proto" -> "docs" [style=dashed];
+  "proto" -> "mocks";
+}
+`,
+		},
+		{
+			name:      "dot with dirs and roots",
+			format:    gogenerate.GraphFormatDOT,
+			showDirs:  true,
+			showRoots: true,
+			wantOutput: `digraph generators {
+  rankdir=LR;
+  "docs" [shape=box];
+  "mocks" [shape=box];
+  "proto" [shape=box];
+  "dir:docs" [shape=folder, label="docs"];
+  "dir:mocks" [shape=folder, label="mocks"];
+  "dir:proto" [shape=folder, label="proto"];
+  "root:docs" [shape=note, label="docs"];
+  "root:mocks" [shape=note, label="mocks"];
+  "root:proto/generated" [shape=note, label="proto/generated"];
+  "proto" -> "docs" [style=dashed];
+  "proto" -> "mocks";
+  "docs" -> "dir:docs" [style=dotted, arrowhead=none];
+  "mocks" -> "dir:mocks" [style=dotted, arrowhead=none];
+  "proto" -> "dir:proto" [style=dotted, arrowhead=none];
+  "docs" -> "root:docs" [style=dotted];
+  "mocks" -> "root:mocks" [style=dotted];
+  "proto" -> "root:proto/generated" [style=dotted];
+}
+`,
+		},
+		{
+			name:     "mermaid",
+			format:   gogenerate.GraphFormatMermaid,
+			showDirs: true,
+			wantOutput: `flowchart LR
+  g0["docs"]
+  g1["mocks"]
+  g2["proto"]
+  d0[("docs")]
+  d1[("mocks")]
+  d2[("proto")]
+  g2 -.-> g0
+  g2 --> g1
+  g0 -.- d0
+  g1 -.- d1
+  g2 -.- d2
+`,
+		},
+	} {
+		tmpDir, cleanup, err := dirs.TempDir(".", "")
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+			{
+				RelPath: "proto/generated/proto.pb.go",
+				Src:     `package generated`,
+			},
+			{
+				RelPath: "proto/generated/proto.md",
+				Src:     `proto`,
+			},
+			{
+				RelPath: "mocks/mocks.go",
+				Src:     `package mocks`,
+			},
+			{
+				RelPath: "docs/docs.md",
+				Src:     `docs`,
+			},
+		})
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		projectParam := gogenerate.ProjectParam{
+			Generators: gogenerate.Generators{
+				"proto": gogenerate.GeneratorParam{
+					GoGenDir:     "proto",
+					GenPaths:     matcher.Path("proto/generated"),
+					GenPathRoots: []string{"proto/generated"},
+				},
+				"mocks": gogenerate.GeneratorParam{
+					GoGenDir:     "mocks",
+					GenPaths:     matcher.Path("mocks/mocks.go"),
+					GenPathRoots: []string{"mocks"},
+					Inputs:       matcher.Name(`.+\.pb\.go`),
+					DependsOn:    []string{"proto"},
+				},
+				"docs": gogenerate.GeneratorParam{
+					GoGenDir:     "docs",
+					GenPaths:     matcher.Path("docs/docs.md"),
+					GenPathRoots: []string{"docs"},
+					Inputs:       matcher.Name(`.+\.md`),
+				},
+			},
+		}
+		buf := &bytes.Buffer{}
+		err = gogenerate.WriteGraph(tmpDir, projectParam, currCase.format, currCase.showDirs, currCase.showRoots, buf)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		assert.Equal(t, currCase.wantOutput, buf.String(), "Case %d: %s", currCaseNum, currCase.name)
+
+		cleanup()
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+)
+
+// GeneratorInfo describes a configured generator and the current state of the project with respect to it.
+type GeneratorInfo struct {
+	// Name is the name of the generator.
+	Name string `json:"name"`
+	// Dir is the slash-separated path of the directory in which "go generate" is run relative to the project directory.
+	Dir string `json:"dir"`
+	// EnvironmentKeys contains the sorted names of the environment variables set for the generator.
+	EnvironmentKeys []string `json:"environmentKeys"`
+	// Directives contains the "//go:generate" directives in Dir.
+	Directives []Directive `json:"directives"`
+	// MatchedPaths contains the sorted slash-separated paths relative to the project directory that are currently
+	// matched by the GenPaths of the generator.
+	MatchedPaths []string `json:"matchedPaths"`
+}
+
+// ListFormat is the format in which the generators are written by ListGenerators.
+type ListFormat string
+
+const (
+	// ListFormatTable writes the generators as a table that is aligned for reading in a terminal.
+	ListFormatTable ListFormat = "table"
+	// ListFormatJSON writes the generators as a JSON array of GeneratorInfo objects.
+	ListFormatJSON ListFormat = "json"
+)
+
+// DescribeGenerators returns the description of every generator in the provided parameters in lexicographical order of
+// their names. Generators whose directory does not exist have no directives. The matched paths are determined as
+// described by matchedPaths.
+func DescribeGenerators(rootDir string, projectParam ProjectParam) ([]GeneratorInfo, error) {
+	matched, err := matchedPaths(rootDir, projectParam)
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []GeneratorInfo
+	for _, k := range projectParam.Generators.SortedKeys() {
+		v := projectParam.Generators[k]
+		dir := path.Clean(filepath.ToSlash(v.GoGenDir))
+		var directives []Directive
+		if info, err := os.Stat(filepath.Join(rootDir, dir)); err == nil && info.IsDir() {
+			if directives, err = DirDirectives(rootDir, dir); err != nil {
+				return nil, err
+			}
+		}
+		envKeys := []string{}
+		for envKey := range v.Environment {
+			envKeys = append(envKeys, envKey)
+		}
+		sort.Strings(envKeys)
+		// encode empty values as empty JSON arrays rather than null
+		if directives == nil {
+			directives = []Directive{}
+		}
+		matchedPaths := matched[k]
+		if matchedPaths == nil {
+			matchedPaths = []string{}
+		}
+		infos = append(infos, GeneratorInfo{
+			Name:            k,
+			Dir:             dir,
+			EnvironmentKeys: envKeys,
+			Directives:      directives,
+			MatchedPaths:    matchedPaths,
+		})
+	}
+	return infos, nil
+}
+
+// matchedPaths returns the sorted slash-separated paths relative to the project directory that are currently matched by
+// the GenPaths of every generator, keyed by the name of the generator. The project is walked in the same manner as when
+// checksums are computed, but paths that cannot be scanned are omitted regardless of the scan error policy.
+func matchedPaths(rootDir string, projectParam ProjectParam) (map[string][]string, error) {
+	scanParam := projectParam
+	scanParam.ScanErrors = ScanErrorPolicy{
+		PermissionDenied: ScanErrorSkip,
+		NotExist:         ScanErrorSkip,
+	}
+	checksums, err := newScanner(rootDir, scanParam, newOptions(nil), io.Discard, projectParam.Generators.SortedKeys()...).scan()
+	if err != nil {
+		return nil, err
+	}
+	matched := make(map[string][]string)
+	for k, v := range projectParam.Generators {
+		for matchedPath := range checksums.forGenerator(k, v) {
+			matched[k] = append(matched[k], filepath.ToSlash(matchedPath))
+		}
+		sort.Strings(matched[k])
+	}
+	return matched, nil
+}
+
+// ListGenerators writes the description of every generator in the provided parameters to the provided writer in the
+// provided format.
+func ListGenerators(rootDir string, projectParam ProjectParam, format ListFormat, stdout io.Writer) error {
+	infos, err := DescribeGenerators(rootDir, projectParam)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case ListFormatJSON:
+		if infos == nil {
+			infos = []GeneratorInfo{}
+		}
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(infos); err != nil {
+			return errors.Wrapf(err, "failed to write generators as JSON")
+		}
+		return nil
+	case ListFormatTable:
+		return writeGeneratorsTable(infos, stdout)
+	default:
+		return errors.Errorf("invalid list format %q: must be one of %q or %q", format, ListFormatTable, ListFormatJSON)
+	}
+}
+
+// writeGeneratorsTable writes one row for every generator. Columns with multiple values are continued on the rows that
+// follow the row of the generator.
+func writeGeneratorsTable(infos []GeneratorInfo, stdout io.Writer) error {
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tDIR\tENVIRONMENT\tDIRECTIVES\tMATCHED PATHS")
+	for _, info := range infos {
+		var directives []string
+		for _, d := range info.Directives {
+			directives = append(directives, fmt.Sprintf("%s:%d: %s", path.Base(d.File), d.Line, d.Command))
+		}
+		columns := [][]string{{info.Name}, {info.Dir}, info.EnvironmentKeys, directives, info.MatchedPaths}
+		rows := 1
+		for _, column := range columns {
+			rows = max(rows, len(column))
+		}
+		for i := 0; i < rows; i++ {
+			var cells []string
+			for _, column := range columns {
+				cell := ""
+				if i < len(column) {
+					cell = column[i]
+				} else if i == 0 {
+					cell = "-"
+				}
+				cells = append(cells, cell)
+			}
+			_, _ = fmt.Fprintln(w, strings.Join(cells, "\t"))
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Wrapf(err, "failed to write generators")
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/nmiyake/pkg/gofiles"
+	"github.com/palantir/go-generate/gogenerate"
+	"github.com/palantir/pkg/matcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListGenerators(t *testing.T) {
+	for currCaseNum, currCase := range []struct {
+		name       string
+		format     gogenerate.ListFormat
+		wantOutput string
+	}{
+		{
+			name:   "table",
+			format: gogenerate.ListFormatTable,
+			wantOutput: `NAME     DIR      ENVIRONMENT  DIRECTIVES                          MATCHED PATHS
+gen      gen      GOARCH       gen.go:3: go run generator_main.go  gen/output.go
+                  GOOS         gen.go:4: stringer -type=Color      gen/output.txt
+missing  missing  -            -                                   -
+`,
+		},
+		{
+			name:   "json",
+			format: gogenerate.ListFormatJSON,
+			wantOutput: `[
+  {
+    "name": "gen",
+    "dir": "gen",
+    "environmentKeys": [
+      "GOARCH",
+      "GOOS"
+    ],
+    "directives": [
+      {
+        "file": "gen/gen.go",
+        "line": 3,
+        "command": "go run generator_main.go"
+      },
+      {
+        "file": "gen/gen.go",
+        "line": 4,
+        "command": "stringer -type=Color"
+      }
+    ],
+    "matchedPaths": [
+      "gen/output.go",
+      "gen/output.txt"
+    ]
+  },
+  {
+    "name": "missing",
+    "dir": "missing",
+    "environmentKeys": [],
+    "directives": [],
+    "matchedPaths": []
+  }
+]
+`,
+		},
+	} {
+		tmpDir, cleanup, err := dirs.TempDir(".", "")
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+			{
+				RelPath: "gen/gen.go",
+				Src: `package gen
+
+//go:generate go run generator_main.go
+//go:generate stringer -type=Color
+`,
+			},
+			{
+				RelPath: "gen/output.go",
+				Src:     `package gen`,
+			},
+			{
+				RelPath: "gen/output.txt",
+				Src:     `output`,
+			},
+		})
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		projectParam := gogenerate.ProjectParam{
+			Generators: gogenerate.Generators{
+				"gen": gogenerate.GeneratorParam{
+					GoGenDir: "gen",
+					GenPaths: matcher.Name(`output\..+`),
+					Environment: map[string]string{
+						"GOOS":   "linux",
+						"GOARCH": "amd64",
+					},
+				},
+				"missing": gogenerate.GeneratorParam{
+					GoGenDir: "missing",
+					GenPaths: matcher.Path("missing/output.txt"),
+				},
+			},
+		}
+		buf := &bytes.Buffer{}
+		err = gogenerate.ListGenerators(tmpDir, projectParam, currCase.format, buf)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		assert.Equal(t, currCase.wantOutput, buf.String(), "Case %d: %s", currCaseNum, currCase.name)
+
+		cleanup()
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// Normalizer specifies the transformations that are applied to the content of generated files before it is compared.
+// Normalizers allow the output of generators that embed volatile content such as timestamps or version banners to be
+// verified. The zero value does not transform content.
+type Normalizer struct {
+	// IgnoreLines contains the regular expressions that match lines that are removed from the content.
+	IgnoreLines []*regexp.Regexp
+	// FoldCRLF specifies whether CRLF line endings are converted to LF.
+	FoldCRLF bool
+	// TrimTrailingWhitespace specifies whether trailing whitespace is removed from every line.
+	TrimTrailingWhitespace bool
+}
+
+// IsZero returns true if the normalizer does not transform content.
+func (n Normalizer) IsZero() bool {
+	return len(n.IgnoreLines) == 0 && !n.FoldCRLF && !n.TrimTrailingWhitespace
+}
+
+// Normalize returns the normalized form of the provided content. Line endings are folded first, then trailing
+// whitespace is trimmed and finally ignored lines are removed.
+func (n Normalizer) Normalize(content []byte) []byte {
+	if n.IsZero() {
+		return content
+	}
+	if n.FoldCRLF {
+		content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+	}
+
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	normalized := make([]byte, 0, len(content))
+	for _, line := range lines {
+		body, newline := bytes.CutSuffix(line, []byte("\n"))
+		if n.TrimTrailingWhitespace {
+			body = bytes.TrimRight(body, " \t\r\f\v")
+		}
+		if n.ignored(body) {
+			continue
+		}
+		normalized = append(normalized, body...)
+		if newline {
+			normalized = append(normalized, '\n')
+		}
+	}
+	return normalized
+}
+
+func (n Normalizer) ignored(line []byte) bool {
+	for _, r := range n.IgnoreLines {
+		if r.Match(line) {
+			return true
+		}
+	}
+	return false
+}
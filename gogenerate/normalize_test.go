@@ -0,0 +1,80 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/palantir/go-generate/gogenerate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	for currCaseNum, currCase := range []struct {
+		name       string
+		normalizer gogenerate.Normalizer
+		in         string
+		want       string
+	}{
+		{
+			name: "zero value does not transform content",
+			in:   "foo  \r\nbar\r\n",
+			want: "foo  \r\nbar\r\n",
+		},
+		{
+			name: "CRLF is folded",
+			normalizer: gogenerate.Normalizer{
+				FoldCRLF: true,
+			},
+			in:   "foo  \r\nbar\r\nbaz",
+			want: "foo  \nbar\nbaz",
+		},
+		{
+			name: "trailing whitespace is trimmed",
+			normalizer: gogenerate.Normalizer{
+				TrimTrailingWhitespace: true,
+			},
+			in:   "foo \t\nbar\nbaz  ",
+			want: "foo\nbar\nbaz",
+		},
+		{
+			name: "ignored lines are removed",
+			normalizer: gogenerate.Normalizer{
+				IgnoreLines: []*regexp.Regexp{
+					regexp.MustCompile(`^// Code generated by protoc-gen-foo v[0-9.]+\.$`),
+					regexp.MustCompile(`^// Timestamp: `),
+				},
+			},
+			in:   "// Code generated by protoc-gen-foo v1.2.3.\n// Timestamp: 2016-01-02\n\npackage foo\n",
+			want: "\npackage foo\n",
+		},
+		{
+			name: "lines are matched after line endings are folded and whitespace is trimmed",
+			normalizer: gogenerate.Normalizer{
+				IgnoreLines: []*regexp.Regexp{
+					regexp.MustCompile(`^// version 1$`),
+				},
+				FoldCRLF:               true,
+				TrimTrailingWhitespace: true,
+			},
+			in:   "// version 1 \r\npackage foo\r\n",
+			want: "package foo\n",
+		},
+	} {
+		got := currCase.normalizer.Normalize([]byte(currCase.in))
+		assert.Equal(t, currCase.want, string(got), "Case %d: %s", currCaseNum, currCase.name)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate
+
+// Option configures the behavior of Run, Verify and the other functions that run generators.
+type Option func(*options)
+
+type options struct {
+	paranoid     bool
+	compileCheck *compileCheck
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Paranoid returns an Option that causes the content of every matched file to be hashed on every scan. By default,
+// the checksum of a file whose size, modification time, inode and mode did not change since the previous scan is
+// reused.
+func Paranoid() Option {
+	return func(o *options) {
+		o.paranoid = true
+	}
+}
+
+// CompileCheck returns an Option that causes the packages that contain the Go files matched by the gen-paths of every
+// generator to be checked with the provided tool after the generator is run. If once is true, the packages of all of
+// the generators are instead checked with a single invocation of the tool after all of the generators are run. The
+// check is only performed by Run and Verify.
+func CompileCheck(tool CompileCheckTool, once bool) Option {
+	return func(o *options) {
+		o.compileCheck = &compileCheck{
+			tool: tool,
+			once: once,
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// GeneratedFile is a Go file that carries the standard "// Code generated ... DO NOT EDIT." header.
+type GeneratedFile struct {
+	// Path is the slash-separated path of the file relative to the project directory.
+	Path string
+	// Line is the 1-based line number of the header.
+	Line int
+	// Header is the header line.
+	Header string
+}
+
+// OrphanedFiles returns the generated Go files in the project that are not matched by the GenPaths of any generator.
+// Directories that are ignored by the go tool and paths matched by the Exclude matcher of the provided parameters are
+// skipped. The returned files are sorted by path.
+func OrphanedFiles(rootDir string, projectParam ProjectParam) ([]GeneratedFile, error) {
+	var orphans []GeneratedFile
+	if err := walkGoFiles(rootDir, projectParam.Exclude, func(relFile string) error {
+		for _, v := range projectParam.Generators {
+			if v.GenPaths != nil && v.GenPaths.Match(relFile) {
+				return nil
+			}
+		}
+		line, header, err := generatedHeader(filepath.Join(rootDir, filepath.FromSlash(relFile)))
+		if err != nil {
+			return err
+		}
+		if line != 0 {
+			orphans = append(orphans, GeneratedFile{
+				Path:   relFile,
+				Line:   line,
+				Header: header,
+			})
+		}
+		return nil
+	}); err != nil {
+		return nil, errors.Wrapf(err, "failed to find orphaned generated files")
+	}
+	return orphans, nil
+}
+
+// CheckOrphans returns true if every generated Go file in the project is matched by the GenPaths of a generator, false
+// otherwise. If the check is not successful, the path and header line of every orphaned file are written as output to
+// the provided writer. If prune is true, orphaned files are removed instead and the check is successful. Returns an error
+// if an error is encountered when running the check itself. Pruning is refused if no generators are configured, since
+// every generated file would be considered to be orphaned (for example, if the configuration file was not found).
+func CheckOrphans(rootDir string, projectParam ProjectParam, prune bool, stdout io.Writer) (bool, error) {
+	if prune && len(projectParam.Generators) == 0 {
+		return false, errors.Errorf("refusing to prune orphaned generated files because no generators are configured")
+	}
+	orphans, err := OrphanedFiles(rootDir, projectParam)
+	if err != nil {
+		return false, err
+	}
+	if len(orphans) == 0 {
+		return true, nil
+	}
+
+	outputParts := []string{"Generated files that are not matched by the gen-paths of any generator:"}
+	if prune {
+		outputParts = []string{"Removed generated files that are not matched by the gen-paths of any generator:"}
+	}
+	for _, orphan := range orphans {
+		if prune {
+			if err := os.Remove(filepath.Join(rootDir, filepath.FromSlash(orphan.Path))); err != nil {
+				return false, errors.Wrapf(err, "failed to remove orphaned generated file")
+			}
+		}
+		outputParts = append(outputParts, fmt.Sprintf("  %s:%d: %s", orphan.Path, orphan.Line, orphan.Header))
+	}
+	_, _ = fmt.Fprintln(stdout, strings.Join(outputParts, "\n"))
+	return prune, nil
+}
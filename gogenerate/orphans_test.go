@@ -0,0 +1,135 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate_test
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/nmiyake/pkg/gofiles"
+	"github.com/palantir/go-generate/gogenerate"
+	"github.com/palantir/pkg/matcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckOrphans(t *testing.T) {
+	for currCaseNum, currCase := range []struct {
+		name       string
+		prune      bool
+		want       bool
+		wantOutput string
+	}{
+		{
+			name: "orphaned files are reported",
+			want: false,
+			wantOutput: `Generated files that are not matched by the gen-paths of any generator:
+  old/color_string.go:3: // Code generated by "stringer -type=Color"; DO NOT EDIT.
+`,
+		},
+		{
+			name:  "orphaned files are pruned",
+			prune: true,
+			want:  true,
+			wantOutput: `Removed generated files that are not matched by the gen-paths of any generator:
+  old/color_string.go:3: // Code generated by "stringer -type=Color"; DO NOT EDIT.
+`,
+		},
+	} {
+		tmpDir, cleanup, err := dirs.TempDir(".", "")
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+			{
+				RelPath: "gen/output.go",
+				Src: `// Code generated by gen. DO NOT EDIT.
+
+package gen
+`,
+			},
+			{
+				RelPath: "old/color_string.go",
+				Src: `// Copyright 2016 Palantir Technologies, Inc.
+
+// Code generated by "stringer -type=Color"; DO NOT EDIT.
+
+package old
+`,
+			},
+			{
+				RelPath: "manual/manual.go",
+				Src: `package manual
+
+// Code generated by hand. DO NOT EDIT.
+`,
+			},
+			{
+				RelPath: "vendor/github.com/foo/foo.go",
+				Src: `// Code generated by foo. DO NOT EDIT.
+
+package foo
+`,
+			},
+		})
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+
+		projectParam := gogenerate.ProjectParam{
+			Generators: gogenerate.Generators{
+				"gen": gogenerate.GeneratorParam{
+					GoGenDir: "gen",
+					GenPaths: matcher.Path("gen/output.go"),
+				},
+			},
+		}
+		buf := &bytes.Buffer{}
+		got, err := gogenerate.CheckOrphans(tmpDir, projectParam, currCase.prune, buf)
+		require.NoError(t, err, "Case %d: %s", currCaseNum, currCase.name)
+		assert.Equal(t, currCase.want, got, "Case %d: %s", currCaseNum, currCase.name)
+		assert.Equal(t, currCase.wantOutput, buf.String(), "Case %d: %s", currCaseNum, currCase.name)
+
+		_, err = os.Stat(path.Join(tmpDir, "old", "color_string.go"))
+		assert.Equal(t, currCase.prune, os.IsNotExist(err), "Case %d: %s", currCaseNum, currCase.name)
+
+		cleanup()
+	}
+}
+
+func TestCheckOrphansPruneWithoutGenerators(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "gen/output.go",
+			Src: `// Code generated by gen. DO NOT EDIT.
+
+package gen
+`,
+		},
+	})
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	_, err = gogenerate.CheckOrphans(tmpDir, gogenerate.ProjectParam{}, true, buf)
+	assert.EqualError(t, err, "refusing to prune orphaned generated files because no generators are configured")
+	assert.Equal(t, "", buf.String())
+
+	_, err = os.Stat(path.Join(tmpDir, "gen", "output.go"))
+	assert.NoError(t, err)
+}
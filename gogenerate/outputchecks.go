@@ -0,0 +1,147 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// generatedHeaderRegexp matches the standard header that marks a Go file as generated (see "go help generate").
+var generatedHeaderRegexp = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// outputProblems maps the name of a generator to the problems found by the output checks of its Go files, which are
+// keyed by the path of the file.
+type outputProblems map[string]map[string]string
+
+func (p outputProblems) add(generator, relPath, problem string) {
+	if p[generator] == nil {
+		p[generator] = make(map[string]string)
+	}
+	p[generator][relPath] = problem
+}
+
+// error returns an error that lists the problems of every generator. Returns nil if there are no problems.
+func (p outputProblems) error() error {
+	if len(p) == 0 {
+		return nil
+	}
+	var sortedKeys []string
+	for k := range p {
+		sortedKeys = append(sortedKeys, k)
+	}
+	sort.Strings(sortedKeys)
+
+	outputParts := []string{fmt.Sprintf("Generators produced Go files that failed output checks: %v", sortedKeys)}
+	for _, k := range sortedKeys {
+		outputParts = append(outputParts, fmt.Sprintf("  %s:", k))
+		var sortedPaths []string
+		for relPath := range p[k] {
+			sortedPaths = append(sortedPaths, relPath)
+		}
+		sort.Strings(sortedPaths)
+		for _, relPath := range sortedPaths {
+			outputParts = append(outputParts, fmt.Sprintf("    %s: %s", relPath, p[k][relPath]))
+		}
+	}
+	return errors.New(strings.Join(outputParts, "\n"))
+}
+
+// checkOutputs runs the output checks specified by the provided generator on the provided Go files that it produced
+// and records any problems. If the generator specifies GofmtFix, files that are not formatted are rewritten and true is
+// returned if any file was rewritten.
+func checkOutputs(rootDir, name string, param GeneratorParam, goFiles []string, problems outputProblems) (bool, error) {
+	rewritten := false
+	for _, relPath := range goFiles {
+		filePath := filepath.Join(rootDir, relPath)
+		if param.RequireGeneratedHeader {
+			line, _, err := generatedHeader(filePath)
+			if err != nil {
+				return false, err
+			}
+			if line == 0 {
+				problems.add(name, relPath, `does not have the "// Code generated ... DO NOT EDIT." header`)
+			}
+		}
+		if param.Gofmt == GofmtNone {
+			continue
+		}
+
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to read %s", filePath)
+		}
+		formatted, err := format.Source(content)
+		if err != nil {
+			problems.add(name, relPath, fmt.Sprintf("is not valid Go: %v", err))
+			continue
+		}
+		if bytes.Equal(content, formatted) {
+			continue
+		}
+		if param.Gofmt == GofmtCheck {
+			problems.add(name, relPath, "is not formatted with gofmt")
+			continue
+		}
+		info, err := os.Stat(filePath)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to stat %s", filePath)
+		}
+		if err := os.WriteFile(filePath, formatted, info.Mode()); err != nil {
+			return false, errors.Wrapf(err, "failed to write %s", filePath)
+		}
+		rewritten = true
+	}
+	return rewritten, nil
+}
+
+// generatedHeader returns the line number and content of the generated code header of the provided Go file. Returns 0
+// if the file does not have the header. As specified by the convention, only the lines before the package clause are
+// examined.
+func generatedHeader(filePath string) (int, string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return 0, "", errors.Wrapf(err, "failed to open %s", filePath)
+	}
+	defer func() {
+		// file is opened for reading only, so safe to ignore errors on close
+		_ = f.Close()
+	}()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if generatedHeaderRegexp.MatchString(line) {
+			return lineNum, line, nil
+		}
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, "", errors.Wrapf(err, "failed to read %s", filePath)
+	}
+	return 0, "", nil
+}
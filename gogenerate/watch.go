@@ -0,0 +1,225 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Watch polls the project for changes every interval until the provided context is done. When files change, the
+// generators that are affected by the changes (as determined by Generators.Affected) are run once no further changes
+// have been observed for the debounce period. Changes to paths matched by the GenPaths of any generator are ignored,
+// and the changes made while generators run are ignored if they are matched by the GenPaths of a generator that ran, so
+// the output written by the generators does not cause them to be run again. Other changes made while generators run,
+// such as edits to their inputs or files written by a generator outside of its GenPaths, are handled like any other
+// change. A status line is written to the provided writer after every run; the output of the generators is only
+// written if the run fails. Directories whose names begin with "." and paths matched by the Exclude matcher of the
+// provided parameters are not polled. Returns an error if the interval is not positive, if the debounce period is
+// negative or if the project cannot be polled.
+func Watch(ctx context.Context, rootDir string, projectParam ProjectParam, interval, debounce time.Duration, stdout io.Writer, opts ...Option) error {
+	if interval <= 0 {
+		return errors.Errorf("watch interval must be positive, was %v", interval)
+	}
+	if debounce < 0 {
+		return errors.Errorf("watch debounce period must not be negative, was %v", debounce)
+	}
+	w := &watcher{
+		rootDir:      rootDir,
+		projectParam: projectParam,
+	}
+	prev, err := w.poll()
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(stdout, "Watching %d generators for changes\n", len(projectParam.Generators))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	pending := make(map[string]struct{})
+	var lastChange time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		curr, err := w.poll()
+		if err != nil {
+			return err
+		}
+		if addPending(pending, prev.changed(curr), projectParam.Generators) {
+			lastChange = time.Now()
+		}
+		prev = curr
+		if len(pending) == 0 || time.Since(lastChange) < debounce {
+			continue
+		}
+
+		var changedPaths []string
+		for changedPath := range pending {
+			changedPaths = append(changedPaths, changedPath)
+		}
+		pending = make(map[string]struct{})
+		affected := projectParam.Generators.Affected(changedPaths)
+		if len(affected) == 0 {
+			continue
+		}
+
+		start := time.Now()
+		outBuf := &bytes.Buffer{}
+		runParam := projectParam
+		runParam.Generators = projectParam.Generators.Subset(affected)
+		runErr := Run(rootDir, runParam, outBuf, opts...)
+
+		status := "ok"
+		if runErr != nil {
+			status = "failed"
+		}
+		_, _ = fmt.Fprintf(stdout, "[%s] %d changed: ran %v in %s: %s\n", start.Format("15:04:05"), len(changedPaths), affected, time.Since(start).Round(time.Millisecond), status)
+		if runErr != nil {
+			for _, output := range []string{outBuf.String(), runErr.Error()} {
+				for line := range strings.SplitSeq(strings.TrimRight(output, "\n"), "\n") {
+					if line != "" {
+						_, _ = fmt.Fprintf(stdout, "    %s\n", line)
+					}
+				}
+			}
+		}
+
+		// poll again so that the output written by the run is not considered to be a change
+		if curr, err = w.poll(); err != nil {
+			return err
+		}
+		if addPending(pending, prev.changed(curr), runParam.Generators) {
+			lastChange = time.Now()
+		}
+		prev = curr
+	}
+}
+
+type watcher struct {
+	rootDir      string
+	projectParam ProjectParam
+}
+
+// fileState is the state of a polled file that is compared to detect changes.
+type fileState struct {
+	size    int64
+	modTime time.Time
+	mode    os.FileMode
+}
+
+// pollSnapshot maps the slash-separated paths of files relative to the project directory to their state.
+type pollSnapshot map[string]fileState
+
+// changed returns the sorted paths that were added, removed or modified in the provided snapshot.
+func (s pollSnapshot) changed(other pollSnapshot) []string {
+	var changed []string
+	for k, v := range s {
+		if otherV, ok := other[k]; !ok || otherV != v {
+			changed = append(changed, k)
+		}
+	}
+	for k := range other {
+		if _, ok := s[k]; !ok {
+			changed = append(changed, k)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func (w *watcher) poll() (pollSnapshot, error) {
+	root := filepath.Join(w.rootDir, ".")
+	snapshot := make(pollSnapshot)
+	if err := filepath.WalkDir(root, func(currPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				// path was removed while it was being walked
+				return nil
+			}
+			return err
+		}
+		relPath, err := filepath.Rel(root, currPath)
+		if err != nil {
+			return err
+		}
+		if relPath == "." {
+			return nil
+		}
+		excluded := w.projectParam.Exclude != nil && w.projectParam.Exclude.Match(relPath)
+		if d.IsDir() {
+			if excluded || strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if excluded {
+			return nil
+		}
+		info, err := d.Info()
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		snapshot[filepath.ToSlash(relPath)] = fileState{
+			size:    info.Size(),
+			modTime: info.ModTime(),
+			mode:    info.Mode(),
+		}
+		return nil
+	}); err != nil {
+		return nil, errors.Wrapf(err, "failed to poll %q for changes", root)
+	}
+	return snapshot, nil
+}
+
+// addPending adds the provided changed paths that are not matched by the GenPaths of any of the provided generators to
+// pending. Returns true if any path was added.
+func addPending(pending map[string]struct{}, changed []string, generators Generators) bool {
+	added := false
+	for _, changedPath := range changed {
+		if isOutput(generators, changedPath) {
+			continue
+		}
+		pending[changedPath] = struct{}{}
+		added = true
+	}
+	return added
+}
+
+// isOutput returns true if the provided path is matched by the GenPaths of any of the provided generators.
+func isOutput(generators Generators, relPath string) bool {
+	for _, v := range generators {
+		if v.GenPaths != nil && v.GenPaths.Match(relPath) {
+			return true
+		}
+	}
+	return false
+}
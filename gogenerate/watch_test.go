@@ -0,0 +1,287 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/nmiyake/pkg/gofiles"
+	"github.com/palantir/go-generate/gogenerate"
+	"github.com/palantir/go-generate/gogenerate/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestWatch(t *testing.T) {
+	testDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(testDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "gen/testbar.go",
+			Src: `package testbar
+
+//go:generate go run generator_main.go
+`,
+		},
+		{
+			RelPath: "gen/generator_main.go",
+			Src: `// +build ignore
+
+package main
+
+import (
+	"os"
+)
+
+func main() {
+	input, err := os.ReadFile("../inputs/input.txt")
+	if err != nil {
+		panic(err)
+	}
+	if err := os.WriteFile("output.txt", input, 0644); err != nil {
+		panic(err)
+	}
+}
+`,
+		},
+	})
+	require.NoError(t, err)
+	err = os.MkdirAll(path.Join(testDir, "inputs"), 0755)
+	require.NoError(t, err)
+	err = os.WriteFile(path.Join(testDir, "inputs", "input.txt"), []byte("foo"), 0644)
+	require.NoError(t, err)
+
+	const configYML = `
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "gen/output.txt"
+    inputs:
+      paths:
+        - "inputs"
+`
+	var cfg config.ProjectConfig
+	err = yaml.Unmarshal([]byte(configYML), &cfg)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	outBuf := &syncBuffer{}
+	watchErr := make(chan error)
+	go func() {
+		watchErr <- gogenerate.Watch(ctx, testDir, cfg.ToParam(), 20*time.Millisecond, 50*time.Millisecond, outBuf)
+	}()
+
+	// wait for the initial poll to complete before changing the input
+	require.Eventually(t, func() bool {
+		return strings.Contains(outBuf.String(), "Watching 1 generators for changes")
+	}, 10*time.Second, 10*time.Millisecond)
+	err = os.WriteFile(path.Join(testDir, "inputs", "input.txt"), []byte("bar"), 0644)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return strings.Contains(outBuf.String(), "ran [foo]")
+	}, 30*time.Second, 10*time.Millisecond, outBuf.String())
+	content, err := os.ReadFile(path.Join(testDir, "gen", "output.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(content))
+
+	// the output written by the run must not cause another run
+	time.Sleep(500 * time.Millisecond)
+	cancel()
+	require.NoError(t, <-watchErr)
+	assert.Equal(t, 1, strings.Count(outBuf.String(), "ran [foo]"), outBuf.String())
+	assert.Regexp(t, `\[\d\d:\d\d:\d\d\] 1 changed: ran \[foo\] in \S+: ok`, outBuf.String())
+}
+
+func TestWatchInvalidDurations(t *testing.T) {
+	for currCaseNum, currCase := range []struct {
+		name     string
+		interval time.Duration
+		debounce time.Duration
+		wantErr  string
+	}{
+		{
+			name:     "interval must be positive",
+			interval: 0,
+			wantErr:  "watch interval must be positive, was 0s",
+		},
+		{
+			name:     "debounce period must not be negative",
+			interval: time.Second,
+			debounce: -time.Second,
+			wantErr:  "watch debounce period must not be negative, was -1s",
+		},
+	} {
+		err := gogenerate.Watch(context.Background(), ".", gogenerate.ProjectParam{}, currCase.interval, currCase.debounce, &bytes.Buffer{})
+		assert.EqualError(t, err, currCase.wantErr, "Case %d: %s", currCaseNum, currCase.name)
+	}
+}
+
+func TestWatchRunChangesOutsideGenPaths(t *testing.T) {
+	testDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(testDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "a/a.go",
+			Src: `package a
+
+//go:generate go run generator_main.go
+`,
+		},
+		{
+			RelPath: "a/generator_main.go",
+			Src: `// +build ignore
+
+package main
+
+import (
+	"os"
+)
+
+func main() {
+	input, err := os.ReadFile("../inputs/input.txt")
+	if err != nil {
+		panic(err)
+	}
+	if err := os.WriteFile("output.txt", input, 0644); err != nil {
+		panic(err)
+	}
+	// the input of generator "b" is not an output of this generator
+	if err := os.WriteFile("../b/input.txt", input, 0644); err != nil {
+		panic(err)
+	}
+}
+`,
+		},
+		{
+			RelPath: "b/b.go",
+			Src: `package b
+
+//go:generate go run generator_main.go
+`,
+		},
+		{
+			RelPath: "b/generator_main.go",
+			Src: `// +build ignore
+
+package main
+
+import (
+	"os"
+)
+
+func main() {
+	input, err := os.ReadFile("input.txt")
+	if err != nil {
+		panic(err)
+	}
+	if err := os.WriteFile("output.txt", input, 0644); err != nil {
+		panic(err)
+	}
+}
+`,
+		},
+	})
+	require.NoError(t, err)
+	err = os.MkdirAll(path.Join(testDir, "inputs"), 0755)
+	require.NoError(t, err)
+	err = os.WriteFile(path.Join(testDir, "inputs", "input.txt"), []byte("foo"), 0644)
+	require.NoError(t, err)
+	err = os.WriteFile(path.Join(testDir, "b", "input.txt"), []byte("foo"), 0644)
+	require.NoError(t, err)
+
+	const configYML = `
+generators:
+  a:
+    go-generate-dir: a
+    gen-paths:
+      paths:
+        - "a/output.txt"
+    inputs:
+      paths:
+        - "inputs"
+  b:
+    go-generate-dir: b
+    gen-paths:
+      paths:
+        - "b/output.txt"
+`
+	var cfg config.ProjectConfig
+	err = yaml.Unmarshal([]byte(configYML), &cfg)
+	require.NoError(t, err)
+	projectParam := cfg.ToParam()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	outBuf := &syncBuffer{}
+	watchErr := make(chan error)
+	go func() {
+		watchErr <- gogenerate.Watch(ctx, testDir, projectParam, 20*time.Millisecond, 50*time.Millisecond, outBuf)
+	}()
+
+	require.Eventually(t, func() bool {
+		return strings.Contains(outBuf.String(), "Watching 2 generators for changes")
+	}, 10*time.Second, 10*time.Millisecond)
+	err = os.WriteFile(path.Join(testDir, "inputs", "input.txt"), []byte("bar"), 0644)
+	require.NoError(t, err)
+
+	// the input of "b" written by the run of "a" causes "b" to be run
+	require.Eventually(t, func() bool {
+		return strings.Contains(outBuf.String(), "ran [b]")
+	}, 30*time.Second, 10*time.Millisecond, outBuf.String())
+	content, err := os.ReadFile(path.Join(testDir, "b", "output.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(content))
+
+	// the outputs written by the runs must not cause further runs
+	time.Sleep(500 * time.Millisecond)
+	cancel()
+	require.NoError(t, <-watchErr)
+	assert.Equal(t, 1, strings.Count(outBuf.String(), "ran [a]"), outBuf.String())
+	assert.Equal(t, 1, strings.Count(outBuf.String(), "ran [b]"), outBuf.String())
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}